@@ -0,0 +1,169 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/fanook/aicli/internal/changelog"
+	"github.com/fanook/aicli/internal/commitmsg"
+	"github.com/fanook/aicli/internal/githelper"
+	"github.com/fanook/aicli/internal/provider"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+)
+
+const defaultChangelogPrompt = "你是一个帮助编写版本发布说明的助手。以下是本次版本的提交记录，已按 Keep a Changelog 的分类分组。请据此编写面向用户的发布说明。\n\n要求：\n- 使用 Markdown，只能使用以下三级标题：{{.Sections}}，没有内容的分类不要输出\n- 每条说明以 \"- \" 开头，从用户角度描述变化，合并重复或相关的提交，不要出现提交哈希\n- 不兼容的变更以 \"**BREAKING:**\" 开头\n- “其他”分组中面向开发者的内部改动（如重构、测试、CI）可以省略\n{{if .Language}}- 说明使用{{.Language}}撰写，三级标题保持英文\n{{end}}- 不要输出版本标题和其他多余内容\n\n版本：{{.Version}}\n\n提交记录：\n{{.Commits}}"
+
+var changelogCmd = &cobra.Command{
+	Use:   "changelog [range]",
+	Short: "根据提交记录生成版本发布说明",
+	Long: `读取指定范围（如 v1.2.0..HEAD，默认为最近的标签到 HEAD）内的提交，
+按照 git-cmt 的 [feat]/[fix] 约定或 Conventional Commits 对提交分类，使用 AI 生成面向用户的发布说明，
+输出 Markdown 或按 Keep a Changelog 格式更新 CHANGELOG.md。`,
+	Example: `  acl changelog
+  acl changelog v1.2.0..HEAD --version v1.3.0 --update`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		repo, err := githelper.Open("")
+		if err != nil {
+			logrus.Fatal(err)
+		}
+
+		revRange := ""
+		if len(args) == 1 {
+			revRange = args[0]
+		} else if tag, err := repo.LatestTag(); err == nil {
+			revRange = tag + "..HEAD"
+		}
+
+		commits, err := repo.Log(githelper.LogOptions{Range: revRange, NoMerges: true})
+		if err != nil {
+			logrus.Fatalf("获取提交记录失败: %v", err)
+		}
+		if len(commits) == 0 {
+			logrus.Infof("范围 %s 内没有提交。", revRange)
+			return
+		}
+
+		version, _ := cmd.Flags().GetString("version")
+
+		guarded, err := guardSecrets(cmd, groupCommits(commits))
+		if err != nil {
+			logrus.Fatal(err)
+		}
+
+		templateStr, err := cmd.Flags().GetString("prompt")
+		if err != nil {
+			logrus.Fatalf("获取 prompt 标志失败: %v", err)
+		}
+
+		if templateStr == "" {
+			templateStr = os.Getenv("AICLI_CHANGELOG_PROMPT")
+		}
+
+		if templateStr == "" {
+			templateStr = defaultChangelogPrompt
+		}
+
+		tmpl, err := template.New("changelog").Parse(templateStr)
+		if err != nil {
+			logrus.Fatalf("解析模板失败: %v", err)
+		}
+
+		language := outputLanguage(cmd, repo)
+		var promptBuffer bytes.Buffer
+		err = tmpl.Execute(&promptBuffer, struct {
+			Version  string
+			Range    string
+			Sections string
+			Commits  string
+			Language string
+		}{
+			Version:  version,
+			Range:    revRange,
+			Sections: "### " + strings.Join(changelog.Sections, "、### "),
+			Commits:  guarded[0],
+			Language: language,
+		})
+		if err != nil {
+			logrus.Fatalf("执行模板失败: %v", err)
+		}
+
+		notes, err := provider.GenerateContent(withLanguage(promptBuffer.String(), templateStr, language))
+		if err != nil {
+			logrus.Fatalf("生成发布说明失败: %v", err)
+		}
+
+		date := time.Now().Format("2006-01-02")
+		update, _ := cmd.Flags().GetBool("update")
+		if !update {
+			fmt.Printf("\n%s\n\n%s\n\n", changelog.Heading(version, date), notes)
+			return
+		}
+
+		file, _ := cmd.Flags().GetString("file")
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(repo.Dir, file)
+		}
+		existing, err := ioutil.ReadFile(file)
+		if err != nil && !os.IsNotExist(err) {
+			logrus.Fatalf("读取 %s 失败: %v", file, err)
+		}
+
+		content := changelog.Insert(string(existing), version, date, notes)
+		if err := ioutil.WriteFile(file, []byte(content), 0644); err != nil {
+			logrus.Fatalf("写入 %s 失败: %v", file, err)
+		}
+		logrus.Infof("已更新 %s", file)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(changelogCmd)
+	changelogCmd.Flags().String("version", "Unreleased", "发布说明对应的版本号，例如 v1.3.0")
+	changelogCmd.Flags().BoolP("update", "u", false, "按 Keep a Changelog 格式更新 CHANGELOG 文件，而不是打印到终端")
+	changelogCmd.Flags().StringP("file", "f", "CHANGELOG.md", "需要更新的 CHANGELOG 文件路径")
+	changelogCmd.Flags().StringP("prompt", "t", "", "自定义提示信息，可使用 {{.Version}} {{.Range}} {{.Sections}} {{.Commits}} {{.Language}}")
+	changelogCmd.Flags().String("lang", "", "生成内容使用的语言，如 zh、en，默认读取仓库 .aicli.yaml 中的 lang 或 AICLI_LANG")
+	changelogCmd.Flags().Bool("allow-secrets", false, "检测到敏感信息时仍然发送（敏感内容会被屏蔽）")
+}
+
+// groupCommits 按 Keep a Changelog 的分类对提交分组，无法归类的提交放入“其他”
+func groupCommits(commits []githelper.Commit) string {
+	groups := map[string][]string{}
+	for _, c := range commits {
+		parsed := commitmsg.Parse(c.Subject)
+		breaking := commitmsg.IsBreaking(c.Subject, c.Body)
+
+		section := changelog.SectionFor(parsed.Type, breaking)
+		if section == "" {
+			section = "其他"
+		}
+
+		entry := parsed.Description
+		if parsed.Scope != "" {
+			entry = parsed.Scope + ": " + entry
+		}
+		if parsed.Type != "" && section == "其他" {
+			entry = fmt.Sprintf("[%s] %s", parsed.Type, entry)
+		}
+		if breaking {
+			entry = "BREAKING: " + entry
+		}
+		groups[section] = append(groups[section], fmt.Sprintf("- %s (%s)", entry, c.ShortHash()))
+	}
+
+	var b strings.Builder
+	for _, section := range append(append([]string(nil), changelog.Sections...), "其他") {
+		if len(groups[section]) == 0 {
+			continue
+		}
+		b.WriteString(fmt.Sprintf("### %s\n%s\n\n", section, strings.Join(groups[section], "\n")))
+	}
+	return strings.TrimSpace(b.String())
+}
@@ -4,10 +4,13 @@ import (
 	"bufio"
 	"bytes"
 	"fmt"
+	"github.com/fanook/aicli/internal/conversation"
 	"github.com/fanook/aicli/internal/provider"
 	"os"
+	"strconv"
 	"strings"
 	"text/template"
+	"unicode"
 
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
@@ -15,12 +18,22 @@ import (
 
 type Conversation struct {
 	History []string
+	// Tree 保存本次会话的全部分支，History 始终对应当前分支
+	Tree *conversation.Tree
 }
 
+const chatHelp = `可用指令:
+  /history              查看当前分支的对话，并显示用户消息编号
+  /edit <编号> <内容>   改写指定编号的用户消息，并从该处重新生成回复
+  /retry                重新生成最后一条回复
+  /branches             列出所有分支
+  /switch <ID>          切换到指定分支
+  /help                 显示本帮助`
+
 var chatCmd = &cobra.Command{
 	Use:     "chat",
 	Short:   "与 AI 进行持续对话",
-	Long:    `使用 AI 进行持续的对话，维持上下文和历史记录。`,
+	Long:    `使用 AI 进行持续的对话，维持上下文和历史记录。支持改写历史消息、重新生成回复，并在多个分支之间切换。`,
 	Example: `  acl chat`,
 	Run: func(cmd *cobra.Command, args []string) {
 		templateStr, err := cmd.Flags().GetString("prompt")
@@ -41,20 +54,21 @@ var chatCmd = &cobra.Command{
 			logrus.Fatalf("解析模板失败: %v", err)
 		}
 
-		conversation := Conversation{
+		session := Conversation{
 			History: []string{},
 		}
 
 		var promptBuffer bytes.Buffer
-		err = tmpl.Execute(&promptBuffer, conversation)
+		err = tmpl.Execute(&promptBuffer, session)
 		if err != nil {
 			logrus.Fatalf("执行模板失败: %v", err)
 		}
 
 		initialPrompt := promptBuffer.String()
-		conversation.History = append(conversation.History, fmt.Sprintf("系统: %s", initialPrompt))
+		session.Tree = conversation.New(initialPrompt)
+		session.History = session.Tree.History()
 
-		fmt.Println("😊 欢迎使用 AI 聊天助手！输入 'exit' 或 'quit' 退出对话。 😊")
+		fmt.Println("😊 欢迎使用 AI 聊天助手！输入 'exit' 或 'quit' 退出对话，输入 /help 查看更多指令。 😊")
 
 		reader := bufio.NewReader(os.Stdin)
 
@@ -72,22 +86,129 @@ var chatCmd = &cobra.Command{
 				break
 			}
 
-			conversation.History = append(conversation.History, fmt.Sprintf("用户: %s", userInput))
-
-			fullPrompt := strings.Join(conversation.History, "\n") + "\nAI:"
-
-			reply, err := provider.GenerateContent(fullPrompt)
-			if err != nil {
-				logrus.Fatalf("生成回复失败: %v", err)
+			if userInput == "" {
+				continue
 			}
 
-			conversation.History = append(conversation.History, fmt.Sprintf("AI: %s", reply))
+			if strings.HasPrefix(userInput, "/") {
+				if handleChatCommand(&session, userInput) {
+					session.reply()
+				}
+				continue
+			}
 
-			fmt.Printf("AI: %s\n", reply)
+			session.Tree.Append(conversation.RoleUser, userInput)
+			session.reply()
 		}
 	},
 }
 
+// reply 根据当前分支生成 AI 回复并追加到对话中
+func (c *Conversation) reply() {
+	reply, err := provider.GenerateContent(c.Tree.Prompt())
+	if err != nil {
+		logrus.Fatalf("生成回复失败: %v", err)
+	}
+
+	c.Tree.Append(conversation.RoleAI, reply)
+	c.History = c.Tree.History()
+
+	fmt.Printf("AI: %s\n", reply)
+}
+
+// handleChatCommand 处理以 / 开头的聊天指令，返回值表示是否需要重新生成回复
+func handleChatCommand(c *Conversation, input string) bool {
+	fields := strings.Fields(input)
+	command := fields[0]
+
+	switch command {
+	case "/help":
+		fmt.Println(chatHelp)
+	case "/history":
+		n := 0
+		for _, msg := range c.Tree.Path() {
+			if msg.Role == conversation.RoleUser {
+				n++
+				fmt.Printf("[%d] %s: %s\n", n, msg.Role, msg.Content)
+			} else {
+				fmt.Printf("    %s: %s\n", msg.Role, msg.Content)
+			}
+		}
+	case "/edit":
+		if len(fields) < 3 {
+			fmt.Println("用法: /edit <编号> <内容>，编号可通过 /history 查看")
+			return false
+		}
+		n, err := strconv.Atoi(fields[1])
+		if err != nil {
+			fmt.Printf("无效的消息编号: %s\n", fields[1])
+			return false
+		}
+		content := afterFields(input, 2)
+		if _, err := c.Tree.Edit(n, content); err != nil {
+			fmt.Println(err)
+			return false
+		}
+		return true
+	case "/retry":
+		if err := c.Tree.Retry(); err != nil {
+			fmt.Println(err)
+			return false
+		}
+		return true
+	case "/branches":
+		for _, leaf := range c.Tree.Leaves() {
+			marker := " "
+			if leaf.ID == c.Tree.Current {
+				marker = "*"
+			}
+			fmt.Printf("%s [%d] %s: %s\n", marker, leaf.ID, leaf.Role, preview(leaf.Content, 40))
+		}
+	case "/switch":
+		if len(fields) < 2 {
+			fmt.Println("用法: /switch <ID>，ID 可通过 /branches 查看")
+			return false
+		}
+		id, err := strconv.Atoi(fields[1])
+		if err != nil {
+			fmt.Printf("无效的分支 ID: %s\n", fields[1])
+			return false
+		}
+		if err := c.Tree.Switch(id); err != nil {
+			fmt.Println(err)
+			return false
+		}
+		c.History = c.Tree.History()
+		fmt.Printf("已切换到分支 [%d]\n", id)
+	default:
+		fmt.Printf("未知指令: %s\n%s\n", command, chatHelp)
+	}
+	return false
+}
+
+// afterFields 返回跳过前 n 个以空白分隔的字段后的原始内容，保留其中的空白
+func afterFields(s string, n int) string {
+	for i := 0; i < n; i++ {
+		s = strings.TrimLeftFunc(s, unicode.IsSpace)
+		j := strings.IndexFunc(s, unicode.IsSpace)
+		if j < 0 {
+			return ""
+		}
+		s = s[j:]
+	}
+	return strings.TrimLeftFunc(s, unicode.IsSpace)
+}
+
+// preview 截取内容的前 n 个字符用于列表展示
+func preview(content string, n int) string {
+	content = strings.ReplaceAll(content, "\n", " ")
+	runes := []rune(content)
+	if len(runes) <= n {
+		return content
+	}
+	return string(runes[:n]) + "..."
+}
+
 func init() {
 	rootCmd.AddCommand(chatCmd)
 	chatCmd.Flags().StringP("prompt", "t", "", "自定义初始化对话的提示信息，例如: --prompt \"你是一个友好的 AI 助手，能够帮助用户解决各种问题。\"")
@@ -0,0 +1,22 @@
+package cmd
+
+import "testing"
+
+func TestAfterFields(t *testing.T) {
+	tests := []struct {
+		input string
+		n     int
+		want  string
+	}{
+		{"/edit 2 hello  world", 2, "hello  world"},
+		{"/edit  2 \t a\tb  c ", 2, "a\tb  c "},
+		{"/edit 2", 2, ""},
+		{"/edit 2 ", 2, ""},
+		{"/switch 3", 1, "3"},
+	}
+	for _, tt := range tests {
+		if got := afterFields(tt.input, tt.n); got != tt.want {
+			t.Errorf("afterFields(%q, %d) = %q, want %q", tt.input, tt.n, got, tt.want)
+		}
+	}
+}
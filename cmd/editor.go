@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// editText 将内容写入临时文件并用编辑器打开，返回去掉首尾空白后的内容
+func editText(initial, pattern string) (string, error) {
+	edited, err := openEditor(initial, pattern)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(edited), nil
+}
+
+// editCode 与 editText 相同，但保留缩进和空行，只去掉编辑器在末尾自动添加的换行，用于编辑代码
+func editCode(initial, pattern string) (string, error) {
+	edited, err := openEditor(initial, pattern)
+	if err != nil {
+		return "", err
+	}
+	if !strings.HasSuffix(initial, "\n") {
+		edited = strings.TrimSuffix(strings.TrimSuffix(edited, "\n"), "\r")
+	}
+	return edited, nil
+}
+
+func openEditor(initial, pattern string) (string, error) {
+	tmpFile, err := ioutil.TempFile("", pattern)
+	if err != nil {
+		return "", fmt.Errorf("创建临时文件失败: %v", err)
+	}
+	defer os.Remove(tmpFile.Name()) // 编辑结束后删除临时文件
+
+	_, err = tmpFile.WriteString(initial)
+	if err != nil {
+		return "", fmt.Errorf("写入临时文件失败: %v", err)
+	}
+	tmpFile.Close()
+
+	editor := os.Getenv("EDITOR")
+	if editor == "" {
+		// 如果未设置 EDITOR 环境变量，使用默认的编辑器
+		if isCommandAvailable("vim") {
+			editor = "vim"
+		} else if isCommandAvailable("nano") {
+			editor = "nano"
+		} else {
+			return "", fmt.Errorf("未设置 EDITOR 环境变量，且系统中未安装 vim 或 nano。")
+		}
+	}
+
+	cmdEditor := exec.Command(editor, tmpFile.Name())
+	cmdEditor.Stdin = os.Stdin
+	cmdEditor.Stdout = os.Stdout
+	cmdEditor.Stderr = os.Stderr
+
+	if err := cmdEditor.Run(); err != nil {
+		return "", fmt.Errorf("打开编辑器失败: %v", err)
+	}
+
+	edited, err := ioutil.ReadFile(tmpFile.Name())
+	if err != nil {
+		return "", fmt.Errorf("读取临时文件失败: %v", err)
+	}
+	return string(edited), nil
+}
+
+func isCommandAvailable(name string) bool {
+	_, err := exec.LookPath(name)
+	return err == nil
+}
@@ -0,0 +1,244 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/fanook/aicli/internal/provider"
+	"github.com/fanook/aicli/internal/shellcmd"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"io/ioutil"
+	"os"
+	"strings"
+	"text/template"
+)
+
+const defaultExplainCmdPrompt = "你是一个解释命令行指令的助手。下面是一条 shell 命令，已经在本地按管道和连接符拆分为若干部分。请逐部分解释每条命令的作用以及各个选项的含义，并给出整条命令的概述。\n\n要求：\n- 只输出 JSON，不要输出其他内容，格式如下：\n{\"summary\": \"整条命令的作用\", \"parts\": [{\"index\": 1, \"explanation\": \"这一部分的作用\", \"flags\": [{\"flag\": \"-l\", \"meaning\": \"选项的含义\"}]}], \"warnings\": [\"需要注意的风险或副作用，没有则为空数组\"]}\n- flag 可以连同它的值一起给出，例如 \"-name '*.log'\"\n- 解释要具体，说明在这条命令中起什么作用，而不仅仅是翻译手册\n\n完整命令：\n{{.Command}}\n\n拆分结果：\n{{.Parts}}"
+
+// explanation 是 AI 返回的命令解释
+type explanation struct {
+	Summary string `json:"summary"`
+	Parts   []struct {
+		Index       int    `json:"index"`
+		Explanation string `json:"explanation"`
+		Flags       []struct {
+			Flag    string `json:"flag"`
+			Meaning string `json:"meaning"`
+		} `json:"flags"`
+	} `json:"parts"`
+	Warnings []string `json:"warnings"`
+}
+
+// explainPart 是本地拆分出的一条命令
+type explainPart struct {
+	Stage shellcmd.Stage
+	// Sep 是这条命令之后的连接符
+	Sep string
+}
+
+// explainCmd 逐部分解释一条 shell 命令
+var explainCmd = &cobra.Command{
+	Use:   "explain-cmd [command]",
+	Short: "逐部分解释一条 shell 命令",
+	Long: `在本地将命令按管道和连接符拆分为多个部分并识别选项，然后使用 AI 解释每个部分及其选项的含义，
+给出整条命令的概述，并结合本地规则检查列出其中的风险操作。命令可以作为参数传入，也可以从标准输入读取。`,
+	Example: `  acl explain-cmd "find . -name '*.log' -mtime +7 | xargs rm -f"
+  echo 'tar -czvf backup.tgz --exclude=node_modules .' | acl explain-cmd`,
+	Run: func(cmd *cobra.Command, args []string) {
+		command := strings.Join(args, " ")
+		if command == "" {
+			input, err := ioutil.ReadAll(os.Stdin)
+			if err != nil {
+				logrus.Fatalf("读取标准输入失败: %v", err)
+			}
+			command = string(input)
+		}
+		command = strings.TrimSpace(command)
+		if command == "" {
+			logrus.Fatal("请提供需要解释的命令")
+		}
+
+		script, err := shellcmd.Parse(command)
+		if err != nil {
+			logrus.Fatalf("解析命令失败: %v", err)
+		}
+
+		parts := splitParts(script)
+		if len(parts) == 0 {
+			logrus.Fatal("命令中没有可解释的内容")
+		}
+
+		guarded, err := guardSecrets(cmd, command, describeParts(parts))
+		if err != nil {
+			logrus.Fatal(err)
+		}
+
+		templateStr, err := cmd.Flags().GetString("prompt")
+		if err != nil {
+			logrus.Fatalf("获取 prompt 标志失败: %v", err)
+		}
+
+		if templateStr == "" {
+			templateStr = os.Getenv("AICLI_EXPLAINCMD_PROMPT")
+		}
+
+		if templateStr == "" {
+			templateStr = defaultExplainCmdPrompt
+		}
+
+		tmpl, err := template.New("explaincmd").Parse(templateStr)
+		if err != nil {
+			logrus.Fatalf("解析模板失败: %v", err)
+		}
+
+		var promptBuffer bytes.Buffer
+		err = tmpl.Execute(&promptBuffer, struct {
+			Command string
+			Parts   string
+		}{
+			Command: guarded[0],
+			Parts:   guarded[1],
+		})
+		if err != nil {
+			logrus.Fatalf("执行模板失败: %v", err)
+		}
+
+		response, err := provider.GenerateContent(promptBuffer.String())
+		if err != nil {
+			logrus.Fatalf("生成解释失败: %v", err)
+		}
+
+		var result explanation
+		start := strings.Index(response, "{")
+		end := strings.LastIndex(response, "}")
+		if start < 0 || end < start {
+			logrus.Warn("回复不是有效的 JSON，直接输出原始内容。")
+			fmt.Printf("\n%s\n\n", response)
+		} else if err := json.Unmarshal([]byte(response[start:end+1]), &result); err != nil {
+			logrus.Warnf("解析解释失败，直接输出原始内容: %v", err)
+			fmt.Printf("\n%s\n\n", response)
+		}
+
+		renderExplanation(command, parts, result, shellcmd.Analyze(command))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(explainCmd)
+	explainCmd.Flags().StringP("prompt", "t", "", "自定义提示信息，可使用 {{.Command}} {{.Parts}}，需要 AI 输出 JSON")
+	explainCmd.Flags().Bool("allow-secrets", false, "检测到敏感信息时仍然发送（敏感内容会被屏蔽）")
+}
+
+// splitParts 按管道和连接符将解析结果拆分为依次执行的部分
+func splitParts(script *shellcmd.Script) []explainPart {
+	var parts []explainPart
+	for _, p := range script.Pipelines {
+		for i, stage := range p.Stages {
+			sep := "|"
+			if i == len(p.Stages)-1 {
+				sep = p.Op
+			}
+			parts = append(parts, explainPart{Stage: stage, Sep: sep})
+		}
+	}
+	return parts
+}
+
+// describeParts 将本地拆分结果整理为提示词中的编号列表。
+// 只有变量赋值或重定向、没有命令的部分（如 FOO=1、> out.txt）同样会列出。
+func describeParts(parts []explainPart) string {
+	var b strings.Builder
+	for i, p := range parts {
+		var flags, operands []string
+		if len(p.Stage.Args) > 0 {
+			for _, arg := range p.Stage.Args[1:] {
+				if strings.HasPrefix(arg, "-") && arg != "-" {
+					flags = append(flags, arg)
+				} else {
+					operands = append(operands, shellcmd.Quote(arg))
+				}
+			}
+		}
+		b.WriteString(fmt.Sprintf("%d. %s\n   ", i+1, p.Stage.String()))
+		if name := p.Stage.Name(); name != "" {
+			b.WriteString("命令: " + name)
+		} else {
+			b.WriteString("没有命令")
+		}
+		if len(p.Stage.Assigns) > 0 {
+			b.WriteString("；变量赋值: " + strings.Join(p.Stage.Assigns, " "))
+		}
+		if len(flags) > 0 {
+			b.WriteString("；选项: " + strings.Join(flags, " "))
+		}
+		if len(operands) > 0 {
+			b.WriteString("；参数: " + strings.Join(operands, " "))
+		}
+		for _, r := range p.Stage.Redirects {
+			b.WriteString(fmt.Sprintf("；重定向: %s%s %s", r.Fd, r.Op, r.Target))
+		}
+		if p.Sep != "" {
+			b.WriteString("；之后的连接符: " + p.Sep)
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// renderExplanation 输出带注释的命令拆解
+func renderExplanation(command string, parts []explainPart, result explanation, report shellcmd.Report) {
+	fmt.Printf("\n命令: %s\n", command)
+
+	for i, p := range parts {
+		fmt.Printf("\n[%d] %s\n", i+1, p.Stage.String())
+		for _, r := range result.Parts {
+			if r.Index != i+1 {
+				continue
+			}
+			if r.Explanation != "" {
+				fmt.Println(indent(r.Explanation, "    "))
+			}
+			width := 0
+			for _, f := range r.Flags {
+				if w := len([]rune(f.Flag)); w > width {
+					width = w
+				}
+			}
+			for _, f := range r.Flags {
+				fmt.Printf("      %s%s  %s\n", f.Flag, strings.Repeat(" ", width-len([]rune(f.Flag))), f.Meaning)
+			}
+		}
+		switch p.Sep {
+		case "|":
+			fmt.Println("  │ 输出通过管道传给下一条命令")
+		case "&&":
+			fmt.Println("  && 上一条成功后才执行下一条")
+		case "||":
+			fmt.Println("  || 上一条失败时才执行下一条")
+		case "&":
+			fmt.Println("  & 放入后台执行")
+		}
+	}
+
+	if result.Summary != "" {
+		fmt.Printf("\n概述: %s\n", result.Summary)
+	}
+
+	if len(report.Risks) > 0 || len(result.Warnings) > 0 {
+		fmt.Println("\n风险提示:")
+		for _, risk := range report.Risks {
+			mark := "⚠️ "
+			if risk.Level == shellcmd.Block {
+				mark = "⛔"
+			}
+			fmt.Printf("  %s [%s] %s\n", mark, risk.Level, risk.Message)
+		}
+		for _, w := range result.Warnings {
+			if strings.TrimSpace(w) != "" {
+				fmt.Printf("  • %s\n", w)
+			}
+		}
+	}
+	fmt.Println()
+}
@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/fanook/aicli/internal/shellcmd"
+)
+
+func TestDescribePartsWithoutCommand(t *testing.T) {
+	tests := []struct {
+		command string
+		want    []string
+	}{
+		{"FOO=1", []string{"没有命令", "变量赋值: FOO=1"}},
+		{"> out.txt", []string{"没有命令", "重定向: > out.txt"}},
+		{"X=1; echo $X", []string{"变量赋值: X=1", "命令: echo", "参数: '$X'"}},
+		{"ls -l | wc -l", []string{"命令: ls", "选项: -l", "之后的连接符: |", "命令: wc"}},
+	}
+	for _, tt := range tests {
+		script, err := shellcmd.Parse(tt.command)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.command, err)
+			continue
+		}
+		got := describeParts(splitParts(script))
+		for _, w := range tt.want {
+			if !strings.Contains(got, w) {
+				t.Errorf("describeParts(%q) = %q, want it to contain %q", tt.command, got, w)
+			}
+		}
+	}
+}
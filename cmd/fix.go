@@ -0,0 +1,219 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/fanook/aicli/internal/cmdsuggest"
+	"github.com/fanook/aicli/internal/provider"
+	"github.com/fanook/aicli/internal/sysinfo"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+const defaultFixPrompt = "你是一个帮助排查命令行错误的助手。用户在终端中执行的命令失败了，请分析失败原因，并给出修正后的命令。\n\n输出格式（严格遵循）：\n原因: 一到两句话说明失败的原因\nCMD: 修正后的命令\n解释: 简要说明修改了什么\n\n如果需要分多步执行，每一步单独输出一组 CMD 和 解释。只能使用已安装的工具。\n\n执行的命令：{{.Command}}\n退出码：{{.ExitCode}}\n执行目录：{{.Dir}}\n{{if .Stderr}}错误输出：\n{{.Stderr}}\n{{end}}\n环境：操作系统 {{.OS}} {{.Arch}}{{if .Distro}}，发行版 {{.Distro}}{{end}}，Shell {{.Shell}}{{if .InGitRepo}}，位于 Git 仓库中（分支 {{.GitBranch}}）{{end}}\n已安装的工具：{{.Tools}}\n当前目录：{{.Listing}}"
+
+// maxStderrLines 是发送给 AI 的错误输出的最大行数，超出时只保留末尾
+const maxStderrLines = 60
+
+// staleState 是清理 shell 状态文件的时间阈值，对应的 shell 通常已经退出
+const staleState = 7 * 24 * time.Hour
+
+// lastCommand 是 shell 集成记录的上一条命令
+type lastCommand struct {
+	Command  string
+	ExitCode int
+	Dir      string
+	Stderr   string
+}
+
+// fixCmd 分析并修正上一条失败的命令
+var fixCmd = &cobra.Command{
+	Use:   "fix [command]",
+	Short: "分析上一条失败的命令并给出修正后的命令",
+	Long: `读取 shell 集成（acl shell-init）记录的上一条命令、退出码以及可选的标准错误，
+结合本机环境信息让 AI 分析失败原因并给出修正后的命令，确认后可直接运行。
+也可以直接传入需要修正的命令。`,
+	Example: `  eval "$(acl shell-init bash --capture-stderr)"
+  acl fix
+  acl fix "tar -xvf archive.tar.gz -C"`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		var last lastCommand
+		if len(args) == 1 {
+			last = lastCommand{Command: args[0], ExitCode: -1}
+			last.Dir, _ = os.Getwd()
+		} else {
+			var err error
+			last, err = readLastCommand()
+			if err != nil {
+				logrus.Fatal(err)
+			}
+			fmt.Printf("上一条命令: %s\n退出码: %d\n", last.Command, last.ExitCode)
+			force, _ := cmd.Flags().GetBool("force")
+			if last.ExitCode == 0 && !force {
+				logrus.Info("上一条命令执行成功，无需修正。如仍需分析请使用 --force。")
+				return
+			}
+		}
+
+		guarded, err := guardSecrets(cmd, last.Command, last.Stderr)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+
+		templateStr, err := cmd.Flags().GetString("prompt")
+		if err != nil {
+			logrus.Fatalf("获取 prompt 标志失败: %v", err)
+		}
+
+		if templateStr == "" {
+			templateStr = os.Getenv("AICLI_FIX_PROMPT")
+		}
+
+		if templateStr == "" {
+			templateStr = defaultFixPrompt
+		}
+
+		tmpl, err := template.New("fix").Parse(templateStr)
+		if err != nil {
+			logrus.Fatalf("解析模板失败: %v", err)
+		}
+
+		exitCode := "未知"
+		if last.ExitCode >= 0 {
+			exitCode = strconv.Itoa(last.ExitCode)
+		}
+
+		var promptBuffer bytes.Buffer
+		err = tmpl.Execute(&promptBuffer, struct {
+			Command  string
+			ExitCode string
+			Dir      string
+			Stderr   string
+			sysinfo.Info
+		}{
+			Command:  guarded[0],
+			ExitCode: exitCode,
+			Dir:      last.Dir,
+			Stderr:   guarded[1],
+			Info:     sysinfo.Collect(),
+		})
+		if err != nil {
+			logrus.Fatalf("执行模板失败: %v", err)
+		}
+
+		response, err := provider.GenerateContent(promptBuffer.String())
+		if err != nil {
+			logrus.Fatalf("生成修正命令失败: %v", err)
+		}
+
+		suggestions := cmdsuggest.Parse(response)
+		if len(suggestions) == 0 {
+			fmt.Printf("\n%s\n\n", response)
+			logrus.Warn("未能从回复中解析出命令。")
+			return
+		}
+		if reason := fixReason(response); reason != "" {
+			fmt.Printf("\n原因: %s\n", reason)
+		}
+
+		if last.Dir != "" {
+			if cwd, _ := os.Getwd(); cwd != last.Dir {
+				logrus.Warnf("当前目录与上一条命令的执行目录 %s 不同", last.Dir)
+			}
+		}
+		os.Exit(handleSuggestions(suggestions).ExitCode)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(fixCmd)
+	fixCmd.Flags().Bool("force", false, "上一条命令执行成功时仍然进行分析")
+	fixCmd.Flags().StringP("prompt", "t", "", "自定义提示信息，可使用 {{.Command}} {{.ExitCode}} {{.Dir}} {{.Stderr}} 以及 gen-cmd 的环境字段")
+	fixCmd.Flags().Bool("allow-secrets", false, "检测到敏感信息时仍然发送（敏感内容会被屏蔽）")
+}
+
+// readLastCommand 读取当前 shell（即父进程）记录的上一条命令，找不到时使用最近一次记录，
+// 同时清理已过期的状态文件
+func readLastCommand() (lastCommand, error) {
+	dir, err := shellStateDir()
+	if err != nil {
+		return lastCommand{}, err
+	}
+
+	path := filepath.Join(dir, fmt.Sprintf("%d.cmd", os.Getppid()))
+	if _, err := os.Stat(path); err != nil {
+		path = latestState(dir)
+	}
+	if path == "" {
+		return lastCommand{}, fmt.Errorf("没有找到上一条命令的记录，请先加载 shell 集成: eval \"$(acl shell-init bash)\"")
+	}
+
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return lastCommand{}, fmt.Errorf("读取命令记录失败: %v", err)
+	}
+	parts := strings.SplitN(strings.TrimRight(string(content), "\n"), "\n", 3)
+	if len(parts) < 3 {
+		return lastCommand{}, fmt.Errorf("命令记录格式错误: %s", path)
+	}
+	code, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil {
+		return lastCommand{}, fmt.Errorf("命令记录格式错误: %s", path)
+	}
+
+	last := lastCommand{ExitCode: code, Dir: parts[1], Command: parts[2]}
+	if stderr, err := ioutil.ReadFile(strings.TrimSuffix(path, ".cmd") + ".stderr"); err == nil {
+		last.Stderr = tailLines(strings.TrimSpace(string(stderr)), maxStderrLines)
+	}
+	return last, nil
+}
+
+// latestState 返回最近修改的命令记录，并删除过期的状态文件
+func latestState(dir string) string {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return ""
+	}
+	latest := ""
+	var latestTime time.Time
+	for _, e := range entries {
+		path := filepath.Join(dir, e.Name())
+		if time.Since(e.ModTime()) > staleState {
+			os.Remove(path)
+			continue
+		}
+		if strings.HasSuffix(e.Name(), ".cmd") && e.ModTime().After(latestTime) {
+			latest, latestTime = path, e.ModTime()
+		}
+	}
+	return latest
+}
+
+func tailLines(s string, n int) string {
+	lines := strings.Split(s, "\n")
+	if len(lines) <= n {
+		return s
+	}
+	return "...\n" + strings.Join(lines[len(lines)-n:], "\n")
+}
+
+// fixReason 提取回复中 "原因:" 之后、第一个 CMD 之前的内容
+func fixReason(response string) string {
+	start := strings.Index(response, "原因")
+	if start < 0 {
+		return ""
+	}
+	reason := response[start+len("原因"):]
+	if end := strings.Index(reason, "CMD"); end >= 0 {
+		reason = reason[:end]
+	}
+	return strings.TrimSpace(strings.TrimLeft(reason, ":：* "))
+}
@@ -3,22 +3,31 @@ package cmd
 import (
 	"bytes"
 	"fmt"
+	"github.com/fanook/aicli/internal/cmdhistory"
+	"github.com/fanook/aicli/internal/cmdsuggest"
 	"github.com/fanook/aicli/internal/provider"
+	"github.com/fanook/aicli/internal/shellcmd"
+	"github.com/fanook/aicli/internal/sysinfo"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"os"
-	"runtime"
 	"strings"
 	"text/template"
 )
 
+const defaultGenCmdPrompt = "你是一个帮助生成命令行指令和解释的助手, 请根据以下描述生成一个适合当前机器的命令行指令，并提供简要的解释：描述：{{.Description}} 操作系统：{{.OS}} 架构：{{.Arch}}{{if .Distro}} 发行版：{{.Distro}}{{end}} Shell：{{.Shell}} 当前目录：{{.Cwd}}（{{.Listing}}）{{if .InGitRepo}} 当前位于 Git 仓库中，分支：{{.GitBranch}}{{end}} 已安装的工具：{{.Tools}}{{if .MissingTools}} 未安装的工具：{{.MissingTools}}，请勿使用未安装的工具{{end}}  生成的格式举例(严格按照此格式)： CMD: free -m \n 解释: 显示当前系统内存使用情况 \n 如果需要分多步执行，每一步单独输出一组 CMD 和 解释。{{if .Examples}} 以下是用户收藏的命令，可参考其风格和习惯用法：{{.Examples}}{{end}}"
+
+const defaultGenCmdAlternativesPrompt = "你是一个帮助生成命令行指令的助手。请根据以下描述，给出 {{.Count}} 种不同的实现方案，方案之间应有实际差异，例如只依赖 POSIX 工具的可移植写法、使用 GNU 扩展的写法、使用更现代工具的写法等。\n\n要求：\n- 只输出 JSON 数组，不要输出其他内容，格式如下：\n[{\"command\": \"命令\", \"explanation\": \"简要解释\", \"portability\": \"可移植性说明，例如仅适用于 GNU/Linux、兼容 macOS 等\", \"tools\": [\"依赖的命令行工具\"]}]\n- 优先使用已安装的工具，使用未安装的工具时需在 portability 中说明\n\n描述：{{.Description}}\n操作系统：{{.OS}} 架构：{{.Arch}}{{if .Distro}} 发行版：{{.Distro}}{{end}} Shell：{{.Shell}}\n当前目录：{{.Cwd}}（{{.Listing}}）{{if .InGitRepo}}\n当前位于 Git 仓库中，分支：{{.GitBranch}}{{end}}\n已安装的工具：{{.Tools}}{{if .MissingTools}}\n未安装的工具：{{.MissingTools}}{{end}}{{if .Examples}}\n\n以下是用户收藏的命令，可参考其风格和习惯用法：{{.Examples}}{{end}}"
+
 // genCmd 定义了 gen-cmd 命令
 var genCmd = &cobra.Command{
-	Use:     "gen-cmd [description]",
-	Short:   "根据描述生成命令行指令及其解释",
-	Long:    `根据用户提供的描述，使用 AI 生成适合当前机器的命令行指令，并提供相应的解释。用户可以查看后手动复制并执行这些命令。`,
-	Example: `  acl gen-cmd "查看当前目录下的文件大小总和"`,
-	Args:    cobra.MinimumNArgs(1),
+	Use:   "gen-cmd [description]",
+	Short: "根据描述生成命令行指令及其解释",
+	Long: `根据用户提供的描述，使用 AI 生成适合当前机器的命令行指令，并提供相应的解释。生成后可以选择直接在当前 shell 中运行、在只读沙箱中试运行、编辑、复制到剪贴板或取消，运行时命令的退出码会作为 gen-cmd 的退出码返回。
+执行前会在本地分析命令的风险：删除、格式化、写入系统路径等危险操作需要输入 yes 确认，极其危险的操作会被拒绝执行。`,
+	Example: `  acl gen-cmd "查看当前目录下的文件大小总和"
+  acl gen-cmd --alternatives 3 "统计每个扩展名的文件数量"`,
+	Args: cobra.MinimumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		description := strings.Join(args, " ")
 
@@ -32,12 +41,20 @@ var genCmd = &cobra.Command{
 			logrus.Fatalf("获取 template 标志失败: %v", err)
 		}
 
+		alternatives, _ := cmd.Flags().GetInt("alternatives")
+		if templateStr == "" && alternatives > 1 {
+			templateStr = os.Getenv("AICLI_GENCMD_ALTERNATIVES_PROMPT")
+			if templateStr == "" {
+				templateStr = defaultGenCmdAlternativesPrompt
+			}
+		}
+
 		if templateStr == "" {
 			templateStr = os.Getenv("AICLI_GENCMD_PROMPT")
 		}
 
 		if templateStr == "" {
-			templateStr = "你是一个帮助生成命令行指令和解释的助手, 请根据以下描述生成一个适合当前机器的命令行指令，并提供简要的解释：描述：{{.Description}} 操作系统：{{.OS}} 架构：{{.Arch}}  生成的格式举例(严格按照此格式)： CMD: free -m \n 解释: 显示当前系统内存使用情况"
+			templateStr = defaultGenCmdPrompt
 		}
 
 		tmpl, err := template.New("gencmd").Parse(templateStr)
@@ -45,15 +62,22 @@ var genCmd = &cobra.Command{
 			logrus.Fatalf("解析模板失败: %v", err)
 		}
 
+		history, err := cmdhistory.Open()
+		if err != nil {
+			logrus.Warnf("打开命令历史失败，本次生成不会被记录: %v", err)
+		}
+
 		var promptBuffer bytes.Buffer
 		err = tmpl.Execute(&promptBuffer, struct {
 			Description string
-			OS          string
-			Arch        string
+			Count       int
+			Examples    string
+			sysinfo.Info
 		}{
 			Description: description,
-			OS:          runtime.GOOS,
-			Arch:        runtime.GOARCH,
+			Count:       alternatives,
+			Examples:    favoriteExamples(history),
+			Info:        sysinfo.Collect(),
 		})
 		if err != nil {
 			logrus.Fatalf("执行模板失败: %v", err)
@@ -66,11 +90,345 @@ var genCmd = &cobra.Command{
 			logrus.Fatalf("生成命令失败: %v", err)
 		}
 
-		fmt.Printf("\n%s\n\n", response)
+		if alternatives > 1 {
+			suggestions, err := cmdsuggest.ParseJSON(response)
+			if err != nil {
+				logrus.Fatal(err)
+			}
+			if printOnly, _ := cmd.Flags().GetBool("print"); printOnly {
+				recordHistory(history, description, response, suggestions[:1])
+				os.Exit(printCommand(suggestions[:1]))
+			}
+			chosen, ok := pickAlternative(suggestions)
+			if !ok {
+				recordHistory(history, description, response, nil)
+				return
+			}
+			os.Exit(runSuggestions(history, description, response, []cmdsuggest.Suggestion{chosen}))
+		}
+
+		suggestions := cmdsuggest.Parse(response)
+		if printOnly, _ := cmd.Flags().GetBool("print"); printOnly {
+			recordHistory(history, description, response, suggestions)
+			os.Exit(printCommand(suggestions))
+		}
+		if len(suggestions) == 0 {
+			recordHistory(history, description, response, nil)
+			fmt.Printf("\n%s\n\n", response)
+			logrus.Warn("未能从回复中解析出命令，请手动复制执行。")
+			return
+		}
+
+		os.Exit(runSuggestions(history, description, response, suggestions))
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(genCmd)
-	genCmd.Flags().StringP("prompt", "t", "", "自定义提示信息，例如: --prompt \"你的提示信息\"")
+	genCmd.Flags().IntP("alternatives", "n", 0, "生成 N 种不同的实现方案（如 POSIX 可移植写法与 GNU 扩展写法）供选择")
+	genCmd.Flags().Bool("print", false, "只将生成的命令输出到标准输出，不进行交互，供 shell-init 的快捷键使用")
+	genCmd.Flags().StringP("prompt", "t", "", "自定义提示信息，可使用 {{.Description}} {{.OS}} {{.Arch}} {{.Distro}} {{.Shell}} {{.Cwd}} {{.Listing}} {{.InGitRepo}} {{.GitBranch}} {{.Tools}} {{.MissingTools}} {{.Examples}}")
+}
+
+// commandOutcome 是用户对命令建议的处理结果
+type commandOutcome struct {
+	// Command 是最终执行的命令，编辑后执行时与生成的命令不同
+	Command  string
+	Executed bool
+	ExitCode int
+}
+
+// handleSuggestions 展示命令建议并让用户选择运行、编辑、复制或取消。
+// 返回结果中的 ExitCode 即 gen-cmd 的退出码。
+func handleSuggestions(suggestions []cmdsuggest.Suggestion) commandOutcome {
+	for {
+		fmt.Println()
+		for i, s := range suggestions {
+			if len(suggestions) > 1 {
+				fmt.Printf("[%d] ", i+1)
+			}
+			fmt.Printf("$ %s\n", strings.ReplaceAll(s.Command, "\n", "\n  "))
+			if s.Explanation != "" {
+				fmt.Printf("%s\n", indent(s.Explanation, "  # "))
+			}
+			printRisks(shellcmd.Analyze(s.Command))
+		}
+
+		prompt := "\n输入 r 运行，d 沙箱试运行，e 编辑，c 复制到剪贴板，q 取消: "
+		if len(suggestions) > 1 {
+			prompt = "\n输入 r 依次运行全部，d 沙箱试运行，e 编辑，c 复制，q 取消（可加编号只处理单条，如 r2、e1）: "
+		}
+		input := strings.ToLower(readLine(prompt))
+		if input == "" || input == "q" {
+			return commandOutcome{}
+		}
+
+		selected := suggestions
+		index := -1
+		if len(input) > 1 {
+			i, ok := candidateIndex(input[1:], len(suggestions))
+			if !ok {
+				fmt.Println("无效的编号")
+				continue
+			}
+			index = i
+			selected = suggestions[i : i+1]
+		}
+
+		switch input[0] {
+		case 'r', 'd':
+			if !checkSafety(selected, input[0] == 'd') {
+				continue
+			}
+			run := runInShell
+			if input[0] == 'd' {
+				if err := shellcmd.DryRunAvailable(); err != nil {
+					fmt.Println(err)
+					continue
+				}
+				fmt.Println("在只读沙箱中试运行：根文件系统只读，/tmp 为临时目录，网络已隔离，写入操作会失败。")
+				run = runDryRun
+			}
+			// 多条命令以 && 连接后在同一个 shell 中执行，遇到失败即停止，
+			// 前面步骤中的 cd、export 等对后续步骤同样生效
+			outcome := commandOutcome{Command: joinCommands(selected), Executed: input[0] == 'r'}
+			if len(selected) > 1 {
+				fmt.Printf("\n$ %s\n", outcome.Command)
+			}
+			code, err := run(outcome.Command)
+			if err != nil {
+				logrus.Fatal(err)
+			}
+			if code != 0 {
+				logrus.Warnf("命令退出码为 %d", code)
+				outcome.ExitCode = code
+			}
+			return outcome
+		case 'e':
+			edited, err := editSuggestions(selected)
+			if err != nil {
+				logrus.Errorf("编辑失败: %v", err)
+				continue
+			}
+			if index >= 0 {
+				rest := append(edited, suggestions[index+1:]...)
+				suggestions = append(suggestions[:index:index], rest...)
+			} else {
+				suggestions = edited
+			}
+		case 'c':
+			if err := copyToClipboard(joinCommands(selected)); err != nil {
+				logrus.Errorf("复制失败: %v", err)
+				continue
+			}
+			fmt.Println("已复制到剪贴板。")
+			return commandOutcome{Command: joinCommands(selected)}
+		default:
+			fmt.Println("无效的输入")
+		}
+	}
+}
+
+// runSuggestions 记录本次生成并交由用户处理，命令执行后更新历史记录，返回 gen-cmd 的退出码
+func runSuggestions(history *cmdhistory.Store, description, response string, suggestions []cmdsuggest.Suggestion) int {
+	id := recordHistory(history, description, response, suggestions)
+	outcome := handleSuggestions(suggestions)
+	if history != nil && id > 0 && outcome.Executed {
+		if err := history.MarkExecuted(id, outcome.Command, outcome.ExitCode); err != nil {
+			logrus.Warnf("更新命令历史失败: %v", err)
+		}
+	}
+	if history != nil {
+		history.Close()
+	}
+	return outcome.ExitCode
+}
+
+// recordHistory 将本次生成写入命令历史，history 为空或写入失败时返回 0
+func recordHistory(history *cmdhistory.Store, description, response string, suggestions []cmdsuggest.Suggestion) int64 {
+	if history == nil {
+		return 0
+	}
+	var explanations []string
+	for _, s := range suggestions {
+		if s.Explanation != "" {
+			explanations = append(explanations, s.Explanation)
+		}
+	}
+	id, err := history.Add(description, response, joinCommands(suggestions), strings.Join(explanations, "\n"))
+	if err != nil {
+		logrus.Warnf("写入命令历史失败: %v", err)
+		return 0
+	}
+	return id
+}
+
+// maxExamples 是作为示例提供给 AI 的收藏命令数量
+const maxExamples = 5
+
+// favoriteExamples 将最近收藏的命令整理为提示词中的示例
+func favoriteExamples(history *cmdhistory.Store) string {
+	if history == nil {
+		return ""
+	}
+	favorites, err := history.Favorites(maxExamples)
+	if err != nil {
+		logrus.Warnf("读取收藏命令失败: %v", err)
+		return ""
+	}
+	var b strings.Builder
+	for _, f := range favorites {
+		b.WriteString(fmt.Sprintf("\n描述：%s\nCMD: %s\n", f.Description, f.Command))
+		if f.Explanation != "" {
+			b.WriteString(fmt.Sprintf("解释: %s\n", strings.ReplaceAll(f.Explanation, "\n", " ")))
+		}
+	}
+	return b.String()
+}
+
+// pickAlternative 展示备选方案的解释、可移植性和依赖工具，由用户选择其中一个
+func pickAlternative(suggestions []cmdsuggest.Suggestion) (cmdsuggest.Suggestion, bool) {
+	for {
+		fmt.Println("\n备选方案:")
+		for i, s := range suggestions {
+			fmt.Printf("\n[%d] $ %s\n", i+1, strings.ReplaceAll(s.Command, "\n", "\n    "))
+			if s.Explanation != "" {
+				fmt.Printf("    %s\n", s.Explanation)
+			}
+			if s.Portability != "" {
+				fmt.Printf("    可移植性: %s\n", s.Portability)
+			}
+			if len(s.Tools) > 0 {
+				var tools []string
+				for _, tool := range s.Tools {
+					if !isCommandAvailable(tool) {
+						tool += "（未安装）"
+					}
+					tools = append(tools, tool)
+				}
+				fmt.Printf("    依赖工具: %s\n", strings.Join(tools, ", "))
+			}
+			printRisks(shellcmd.Analyze(s.Command))
+		}
+
+		input := readLine("\n输入编号选择方案，q 取消: ")
+		if input == "" || input == "q" {
+			return cmdsuggest.Suggestion{}, false
+		}
+		i, ok := candidateIndex(input, len(suggestions))
+		if !ok {
+			fmt.Println("无效的编号")
+			continue
+		}
+		return suggestions[i], true
+	}
+}
+
+// printCommand 将命令输出到标准输出，多步命令以 && 连接，解释和风险提示输出到标准错误。
+// 包含 block 级风险时不输出命令，返回非零退出码。
+func printCommand(suggestions []cmdsuggest.Suggestion) int {
+	if len(suggestions) == 0 {
+		fmt.Fprintln(os.Stderr, "未能从回复中解析出命令")
+		return 1
+	}
+
+	for _, s := range suggestions {
+		if s.Explanation != "" {
+			fmt.Fprintf(os.Stderr, "# %s\n", strings.ReplaceAll(s.Explanation, "\n", " "))
+		}
+	}
+	command := joinCommands(suggestions)
+
+	report := shellcmd.Analyze(command)
+	for _, risk := range report.Risks {
+		fmt.Fprintf(os.Stderr, "# [%s] %s\n", risk.Level, risk.Message)
+	}
+	if report.Level == shellcmd.Block {
+		fmt.Fprintln(os.Stderr, "# 命令包含极其危险的操作，已拒绝输出")
+		return 1
+	}
+
+	fmt.Println(command)
+	return 0
+}
+
+// printRisks 输出命令的风险分析结果
+func printRisks(report shellcmd.Report) {
+	for _, risk := range report.Risks {
+		mark := "⚠️ "
+		if risk.Level == shellcmd.Block {
+			mark = "⛔"
+		}
+		fmt.Printf("  %s %s\n", mark, risk.Message)
+	}
+}
+
+// checkSafety 在执行前检查命令的风险：存在 block 级风险时拒绝执行，
+// 存在 warn 级风险时要求用户输入 yes 确认。试运行在沙箱中进行，warn 级风险无需确认。
+func checkSafety(suggestions []cmdsuggest.Suggestion, dryRun bool) bool {
+	level := shellcmd.Safe
+	for _, s := range suggestions {
+		if report := shellcmd.Analyze(s.Command); report.Level > level {
+			level = report.Level
+		}
+	}
+
+	switch {
+	case level == shellcmd.Block:
+		fmt.Println("⛔ 命令包含极其危险的操作，已拒绝执行。如确有需要，请复制后自行检查并手动执行。")
+		return false
+	case level == shellcmd.Warn && !dryRun:
+		if readLine("⚠️  命令存在风险，请输入 yes 确认执行: ") != "yes" {
+			fmt.Println("已取消执行。")
+			return false
+		}
+	}
+	return true
+}
+
+// editSuggestions 在编辑器中修改命令，多条命令之间以空行分隔。编辑后命令数量不变时保留原有解释。
+func editSuggestions(suggestions []cmdsuggest.Suggestion) ([]cmdsuggest.Suggestion, error) {
+	var commands []string
+	for _, s := range suggestions {
+		commands = append(commands, s.Command)
+	}
+	edited, err := editText(strings.Join(commands, "\n\n"), "gencmd_*.sh")
+	if err != nil {
+		return nil, err
+	}
+
+	var result []cmdsuggest.Suggestion
+	for _, block := range strings.Split(edited, "\n\n") {
+		if block = strings.TrimSpace(block); block != "" {
+			result = append(result, cmdsuggest.Suggestion{Command: block})
+		}
+	}
+	if len(result) == 0 {
+		return nil, fmt.Errorf("命令为空")
+	}
+	if len(result) == len(suggestions) {
+		for i := range result {
+			if result[i].Command == suggestions[i].Command {
+				result[i].Explanation = suggestions[i].Explanation
+			}
+		}
+	}
+	return result, nil
+}
+
+// joinCommands 将多条命令以 && 连接为一个脚本，前一条失败时不再执行后续命令。
+// 多行命令、带注释或以 ; & 结尾的命令用 { } 包裹，避免 && 被吞掉或破坏 heredoc。
+func joinCommands(suggestions []cmdsuggest.Suggestion) string {
+	if len(suggestions) == 1 {
+		return suggestions[0].Command
+	}
+	var commands []string
+	for _, s := range suggestions {
+		command := strings.TrimSpace(s.Command)
+		if strings.ContainsAny(command, "\n#") || strings.HasSuffix(command, ";") || strings.HasSuffix(command, "&") {
+			command = "{ " + command + "\n}"
+		}
+		commands = append(commands, command)
+	}
+	return strings.Join(commands, " && ")
 }
@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"os/exec"
+	"testing"
+
+	"github.com/fanook/aicli/internal/cmdsuggest"
+)
+
+func TestJoinCommands(t *testing.T) {
+	steps := func(commands ...string) []cmdsuggest.Suggestion {
+		var result []cmdsuggest.Suggestion
+		for _, c := range commands {
+			result = append(result, cmdsuggest.Suggestion{Command: c})
+		}
+		return result
+	}
+	tests := []struct {
+		name   string
+		steps  []cmdsuggest.Suggestion
+		want   string
+		output string
+	}{
+		{
+			name:   "single command unchanged",
+			steps:  steps("echo a; echo b"),
+			want:   "echo a; echo b",
+			output: "a\nb\n",
+		},
+		{
+			name:   "state carries over",
+			steps:  steps("cd /", "X=1", "echo $PWD$X"),
+			want:   "cd / && X=1 && echo $PWD$X",
+			output: "/1\n",
+		},
+		{
+			name:   "stops at first failure",
+			steps:  steps("echo a", "false", "echo b"),
+			want:   "echo a && false && echo b",
+			output: "a\n",
+		},
+		{
+			name:   "comment and heredoc are grouped",
+			steps:  steps("echo a # note", "cat <<EOF\nb\nEOF", "echo c;"),
+			want:   "{ echo a # note\n} && { cat <<EOF\nb\nEOF\n} && { echo c;\n}",
+			output: "a\nb\nc\n",
+		},
+	}
+	_, err := exec.LookPath("sh")
+	for _, tt := range tests {
+		got := joinCommands(tt.steps)
+		if got != tt.want {
+			t.Errorf("%s: joinCommands() = %q, want %q", tt.name, got, tt.want)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		out, _ := exec.Command("sh", "-c", got).Output()
+		if string(out) != tt.output {
+			t.Errorf("%s: running %q printed %q, want %q", tt.name, got, out, tt.output)
+		}
+	}
+}
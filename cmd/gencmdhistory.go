@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/fanook/aicli/internal/cmdhistory"
+	"github.com/fanook/aicli/internal/cmdsuggest"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"os"
+	"strings"
+)
+
+// genCmdHistoryCmd 查看、搜索、重新执行和收藏 gen-cmd 生成过的命令
+var genCmdHistoryCmd = &cobra.Command{
+	Use:   "history [search]",
+	Short: "查看和搜索 gen-cmd 生成过的命令，支持重新执行和收藏",
+	Long: `gen-cmd 的每次生成都会记录在本地 SQLite 数据库中，包括描述、命令、是否执行以及退出码。
+可以按关键字搜索描述和命令，重新执行某条记录，或将常用命令加入收藏。
+收藏的命令会作为示例提供给 AI，使之后生成的命令更符合个人习惯。`,
+	Example: `  acl gen-cmd history
+  acl gen-cmd history docker --limit 50
+  acl gen-cmd history --star 12
+  acl gen-cmd history --run 12
+  acl gen-cmd history --starred`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		history, err := cmdhistory.Open()
+		if err != nil {
+			logrus.Fatalf("打开命令历史失败: %v", err)
+		}
+		defer history.Close()
+
+		if id, _ := cmd.Flags().GetInt64("star"); id > 0 {
+			if err := history.SetStarred(id, true); err != nil {
+				logrus.Fatal(err)
+			}
+			logrus.Infof("已收藏 #%d，之后生成命令时会将其作为示例。", id)
+			return
+		}
+		if id, _ := cmd.Flags().GetInt64("unstar"); id > 0 {
+			if err := history.SetStarred(id, false); err != nil {
+				logrus.Fatal(err)
+			}
+			logrus.Infof("已取消收藏 #%d。", id)
+			return
+		}
+		if id, _ := cmd.Flags().GetInt64("run"); id > 0 {
+			entry, err := history.Get(id)
+			if err != nil {
+				logrus.Fatal(err)
+			}
+			if entry.Command == "" {
+				logrus.Fatalf("记录 #%d 中没有可执行的命令", id)
+			}
+			fmt.Printf("描述: %s\n", entry.Description)
+			suggestions := []cmdsuggest.Suggestion{{Command: entry.Command, Explanation: entry.Explanation}}
+			code := runSuggestions(history, entry.Description, entry.Response, suggestions)
+			os.Exit(code)
+		}
+
+		keyword := ""
+		if len(args) == 1 {
+			keyword = args[0]
+		}
+		starred, _ := cmd.Flags().GetBool("starred")
+		limit, _ := cmd.Flags().GetInt("limit")
+		entries, err := history.Search(keyword, starred, limit)
+		if err != nil {
+			logrus.Fatalf("查询命令历史失败: %v", err)
+		}
+		if len(entries) == 0 {
+			logrus.Info("没有找到匹配的记录。")
+			return
+		}
+
+		// 按时间正序输出，最新的记录在最下方，靠近提示符
+		for i := len(entries) - 1; i >= 0; i-- {
+			printHistoryEntry(entries[i])
+		}
+		fmt.Println("\n使用 --run <编号> 重新执行，--star <编号> 收藏。")
+	},
+}
+
+func init() {
+	genCmd.AddCommand(genCmdHistoryCmd)
+	genCmdHistoryCmd.Flags().IntP("limit", "l", 20, "最多显示的记录数")
+	genCmdHistoryCmd.Flags().BoolP("starred", "s", false, "只显示收藏的命令")
+	genCmdHistoryCmd.Flags().Int64("run", 0, "重新执行指定编号的命令，执行前同样会进行风险检查")
+	genCmdHistoryCmd.Flags().Int64("star", 0, "收藏指定编号的命令")
+	genCmdHistoryCmd.Flags().Int64("unstar", 0, "取消收藏指定编号的命令")
+}
+
+func printHistoryEntry(e cmdhistory.Entry) {
+	mark := " "
+	if e.Starred {
+		mark = "★"
+	}
+	status := "未执行"
+	if e.Executed {
+		status = fmt.Sprintf("退出码 %d", e.ExitCode)
+	}
+	fmt.Printf("\n%s #%d  %s  %s  %s\n", mark, e.ID, e.CreatedAt.Local().Format("2006-01-02 15:04"), status, e.Description)
+	if e.Command != "" {
+		fmt.Printf("    $ %s\n", strings.ReplaceAll(e.Command, "\n", "\n      "))
+	}
+	if e.ExecutedCommand != "" && e.ExecutedCommand != e.Command {
+		fmt.Printf("  实际执行:\n    $ %s\n", strings.ReplaceAll(e.ExecutedCommand, "\n", "\n      "))
+	}
+}
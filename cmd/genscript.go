@@ -0,0 +1,181 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/fanook/aicli/internal/provider"
+	"github.com/fanook/aicli/internal/shellcmd"
+	"github.com/fanook/aicli/internal/sysinfo"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+	"text/template"
+)
+
+const defaultGenScriptPrompt = "你是一个编写 bash 脚本的助手。请根据以下描述编写一个完整、可直接运行的 bash 脚本。\n\n要求：\n- 第一行为 #!/usr/bin/env bash，紧接着使用 set -euo pipefail\n- 支持命令行参数解析，提供 -h/--help 输出用法说明，缺少必要参数时给出提示并以非零状态退出\n- 按步骤拆分为函数，关键步骤添加简洁的注释\n- 变量引用加双引号，临时文件使用 mktemp 并通过 trap 清理\n- 只使用已安装的工具\n- 只输出脚本本身，放在一个 ```bash 代码块中\n\n描述：{{.Description}}\n\n环境：操作系统 {{.OS}} {{.Arch}}{{if .Distro}}，发行版 {{.Distro}}{{end}}\n已安装的工具：{{.Tools}}{{if .MissingTools}}\n未安装的工具：{{.MissingTools}}{{end}}"
+
+// genScriptCmd 根据描述生成 bash 脚本
+var genScriptCmd = &cobra.Command{
+	Use:   "gen-script [description]",
+	Short: "根据描述生成完整的 bash 脚本",
+	Long: `根据描述使用 AI 生成包含 set -euo pipefail、参数解析和注释的 bash 脚本。
+生成后在本地使用 bash -n 检查语法，安装了 shellcheck 时同时进行静态检查，
+发现问题会把诊断信息反馈给 AI 修正，最后写入文件并添加可执行权限。`,
+	Example: `  acl gen-script "备份指定目录到 S3，保留最近 7 份" -o backup.sh
+  acl gen-script "批量把目录下的 png 转为 webp" --max-retries 5`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		description := strings.Join(args, " ")
+		output, _ := cmd.Flags().GetString("output")
+		force, _ := cmd.Flags().GetBool("force")
+		maxRetries, _ := cmd.Flags().GetInt("max-retries")
+
+		if _, err := os.Stat(output); err == nil && !force {
+			if !confirm(fmt.Sprintf("文件 %s 已存在，是否覆盖？", output)) {
+				logrus.Info("已取消。")
+				return
+			}
+		}
+
+		templateStr, err := cmd.Flags().GetString("prompt")
+		if err != nil {
+			logrus.Fatalf("获取 prompt 标志失败: %v", err)
+		}
+
+		if templateStr == "" {
+			templateStr = os.Getenv("AICLI_GENSCRIPT_PROMPT")
+		}
+
+		if templateStr == "" {
+			templateStr = defaultGenScriptPrompt
+		}
+
+		tmpl, err := template.New("genscript").Parse(templateStr)
+		if err != nil {
+			logrus.Fatalf("解析模板失败: %v", err)
+		}
+
+		var promptBuffer bytes.Buffer
+		err = tmpl.Execute(&promptBuffer, struct {
+			Description string
+			sysinfo.Info
+		}{
+			Description: description,
+			Info:        sysinfo.Collect(),
+		})
+		if err != nil {
+			logrus.Fatalf("执行模板失败: %v", err)
+		}
+		prompt := promptBuffer.String()
+
+		response, err := provider.GenerateContent(prompt)
+		if err != nil {
+			logrus.Fatalf("生成脚本失败: %v", err)
+		}
+		script := extractScript(response)
+
+		for attempt := 1; ; attempt++ {
+			diagnostics, err := checkScript(script)
+			if err != nil {
+				logrus.Fatal(err)
+			}
+			if diagnostics == "" {
+				break
+			}
+			if attempt > maxRetries {
+				fmt.Fprintf(os.Stderr, "\n%s\n\n", diagnostics)
+				logrus.Warn("多次修正后脚本仍有以上问题，请手动检查。")
+				break
+			}
+
+			logrus.Warnf("脚本检查未通过，正在让 AI 修正 (%d/%d)", attempt, maxRetries)
+			fixPrompt := fmt.Sprintf("%s\n\n你上一次生成的脚本：\n```bash\n%s\n```\n\n本地检查发现以下问题：\n%s\n\n请修正这些问题后输出完整的脚本，仍然放在一个 ```bash 代码块中。", prompt, script, diagnostics)
+			response, err = provider.GenerateContent(fixPrompt)
+			if err != nil {
+				logrus.Fatalf("修正脚本失败: %v", err)
+			}
+			script = extractScript(response)
+		}
+
+		fmt.Printf("\n%s\n\n", script)
+		for _, risk := range shellcmd.Analyze(script).Risks {
+			if risk.Rule != "parse" {
+				fmt.Printf("⚠️  %s: %s\n", risk.Message, risk.Command)
+			}
+		}
+
+		if err := ioutil.WriteFile(output, []byte(script+"\n"), 0755); err != nil {
+			logrus.Fatalf("写入脚本失败: %v", err)
+		}
+		// 覆盖已有文件时 WriteFile 不会修改权限
+		if err := os.Chmod(output, 0755); err != nil {
+			logrus.Fatalf("设置可执行权限失败: %v", err)
+		}
+		logrus.Infof("脚本已写入 %s", output)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(genScriptCmd)
+	genScriptCmd.Flags().StringP("output", "o", "script.sh", "脚本的输出路径")
+	genScriptCmd.Flags().BoolP("force", "f", false, "输出文件已存在时直接覆盖")
+	genScriptCmd.Flags().Int("max-retries", 3, "检查未通过时让 AI 修正的最大次数")
+	genScriptCmd.Flags().StringP("prompt", "t", "", "自定义提示信息，可使用 {{.Description}} 以及 gen-cmd 的环境字段")
+}
+
+// extractScript 取出回复中的第一个代码块，没有代码块时使用整个回复
+func extractScript(response string) string {
+	start := strings.Index(response, "```")
+	if start < 0 {
+		return strings.TrimSpace(response)
+	}
+	rest := response[start+3:]
+	if nl := strings.Index(rest, "\n"); nl >= 0 {
+		rest = rest[nl+1:]
+	}
+	if end := strings.Index(rest, "```"); end >= 0 {
+		rest = rest[:end]
+	}
+	return strings.TrimSpace(rest)
+}
+
+// checkScript 检查脚本的基本要求、bash 语法以及 shellcheck 诊断，没有问题时返回空字符串。
+// shellcheck 只报告 warning 及以上级别的问题，避免风格建议导致反复重新生成。
+func checkScript(script string) (string, error) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		return "", fmt.Errorf("未找到 bash，无法检查脚本语法: %v", err)
+	}
+
+	var problems []string
+	if !strings.HasPrefix(script, "#!") {
+		problems = append(problems, "缺少 shebang，第一行应为 #!/usr/bin/env bash")
+	}
+	if !strings.Contains(script, "set -euo pipefail") {
+		problems = append(problems, "缺少 set -euo pipefail")
+	}
+
+	tmpFile, err := ioutil.TempFile("", "genscript_*.sh")
+	if err != nil {
+		return "", fmt.Errorf("创建临时文件失败: %v", err)
+	}
+	defer os.Remove(tmpFile.Name())
+	if _, err := tmpFile.WriteString(script + "\n"); err != nil {
+		return "", fmt.Errorf("写入临时文件失败: %v", err)
+	}
+	tmpFile.Close()
+
+	if out, err := exec.Command("bash", "-n", tmpFile.Name()).CombinedOutput(); err != nil {
+		problems = append(problems, "bash -n 语法检查失败:\n"+strings.ReplaceAll(strings.TrimSpace(string(out)), tmpFile.Name(), "script.sh"))
+	}
+
+	if isCommandAvailable("shellcheck") {
+		out, err := exec.Command("shellcheck", "--format=gcc", "--shell=bash", "--severity=warning", tmpFile.Name()).CombinedOutput()
+		if err != nil && len(out) > 0 {
+			problems = append(problems, "shellcheck 诊断:\n"+strings.ReplaceAll(strings.TrimSpace(string(out)), tmpFile.Name(), "script.sh"))
+		}
+	}
+	return strings.Join(problems, "\n\n"), nil
+}
@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestCheckScript(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash 未安装")
+	}
+	const header = "#!/usr/bin/env bash\nset -euo pipefail\n"
+	tests := []struct {
+		name   string
+		script string
+		want   []string
+	}{
+		{"valid", header + "echo \"ok\"", nil},
+		{"missing shebang", "set -euo pipefail\necho ok", []string{"shebang"}},
+		{"missing strict mode", "#!/usr/bin/env bash\necho ok", []string{"set -euo pipefail"}},
+		{"syntax error", header + "if true; then\necho ok", []string{"bash -n", "script.sh"}},
+	}
+	for _, tt := range tests {
+		got, err := checkScript(tt.script)
+		if err != nil {
+			t.Errorf("%s: checkScript() error: %v", tt.name, err)
+			continue
+		}
+		if len(tt.want) == 0 && got != "" {
+			t.Errorf("%s: checkScript() = %q, want no problems", tt.name, got)
+		}
+		for _, w := range tt.want {
+			if !strings.Contains(got, w) {
+				t.Errorf("%s: checkScript() = %q, want it to mention %q", tt.name, got, w)
+			}
+		}
+	}
+}
+
+func TestCheckScriptWithoutBash(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+	if _, err := checkScript("#!/usr/bin/env bash\nset -euo pipefail\n"); err == nil || !strings.Contains(err.Error(), "bash") {
+		t.Errorf("checkScript() without bash = %v, want an error about bash", err)
+	}
+}
@@ -0,0 +1,235 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/fanook/aicli/internal/githelper"
+	"github.com/fanook/aicli/internal/provider"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"os"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+const defaultBranchPattern = "{{.Type}}/{{if .Ticket}}{{.Ticket}}-{{end}}{{.Slug}}"
+
+const defaultBranchPrompt = "你是一个帮助命名 Git 分支的助手。请根据以下任务描述，给出分支类型和简短的英文 slug。\n\n要求：\n- type 只能是 {{.Types}} 之一\n- slug 使用 2 到 5 个小写英文单词，以连字符连接，概括任务内容\n- 只输出 JSON，不要输出其他内容，格式如下：\n{\"type\": \"feat\", \"slug\": \"add-user-profile\"}\n\n任务描述：{{.Description}}"
+
+// branchTypes 是分支名中允许使用的类型
+var branchTypes = []string{"feat", "fix", "refactor", "docs", "test", "chore", "perf", "build", "ci", "hotfix"}
+
+var ticketPattern = regexp.MustCompile(`\b[A-Z][A-Z0-9]+-\d+\b`)
+
+// notTickets 是形似任务编号的常见技术名词前缀，例如 UTF-8、SHA-256、ISO-8859
+var notTickets = map[string]bool{
+	"UTF": true, "UCS": true, "ISO": true, "SHA": true, "MD": true, "AES": true, "RSA": true, "ECDSA": true,
+	"HTTP": true, "TLS": true, "SSL": true, "IPV": true, "RFC": true, "ES": true, "MP": true,
+}
+
+var slugInvalid = regexp.MustCompile(`[^a-z0-9]+`)
+
+// branchSuggestion 是 AI 给出的分支信息
+type branchSuggestion struct {
+	Type string `json:"type"`
+	Slug string `json:"slug"`
+}
+
+var gitBranchCmd = &cobra.Command{
+	Use:   "git-branch [task description]",
+	Short: "根据任务描述生成分支名并创建分支",
+	Long: `根据任务描述使用 AI 生成符合团队规范的分支名并创建分支。
+分支名格式由 --pattern 或 AICLI_BRANCH_PATTERN 指定，可使用 {{.Type}} {{.Ticket}} {{.Slug}}，
+任务编号（如 JIRA-123）会从描述中自动识别，也可通过 --ticket 指定。`,
+	Example: `  acl git-branch "JIRA-123 用户资料页支持上传头像"
+  acl git-branch --pattern "{{.Ticket}}/{{.Slug}}" "修复登录超时"`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		description := strings.Join(args, " ")
+
+		repo, err := githelper.Open("")
+		if err != nil {
+			logrus.Fatal(err)
+		}
+
+		ticket, _ := cmd.Flags().GetString("ticket")
+		if ticket == "" {
+			ticket = findTicket(description)
+		}
+
+		suggestion, err := suggestBranch(cmd, description)
+		if err != nil {
+			logrus.Fatalf("生成分支名失败: %v", err)
+		}
+
+		pattern, _ := cmd.Flags().GetString("pattern")
+		if pattern == "" {
+			pattern = os.Getenv("AICLI_BRANCH_PATTERN")
+		}
+		if pattern == "" {
+			pattern = defaultBranchPattern
+		}
+
+		// 回复中的 slug 不含英文时（例如直接返回了中文），改用描述中的英文单词
+		slug := normalizeSlug(suggestion.Slug)
+		if slug == "" {
+			slug = normalizeSlug(strings.Replace(description, ticket, "", 1))
+		}
+		if slug == "" {
+			slug = "task"
+		}
+
+		maxLength, _ := cmd.Flags().GetInt("max-length")
+		name, err := renderBranchName(pattern, suggestion.Type, ticket, slug, maxLength)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		if err := repo.CheckBranchName(name); err != nil {
+			logrus.Fatalf("%v，请检查 --pattern 或 AICLI_BRANCH_PATTERN", err)
+		}
+
+		if repo.BranchExists(name) {
+			logrus.Fatalf("分支 %s 已存在。", name)
+		}
+
+		fmt.Printf("\n生成的分支名: %s\n\n", name)
+
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		if dryRun {
+			return
+		}
+
+		if !confirm("创建并切换到该分支？") {
+			logrus.Info("操作已取消。")
+			return
+		}
+
+		if err := repo.CreateBranch(name, true); err != nil {
+			logrus.Fatalf("创建分支失败: %v", err)
+		}
+		logrus.Infof("已切换到新分支 %s", name)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(gitBranchCmd)
+	gitBranchCmd.Flags().String("pattern", "", "分支名模板，默认为 \""+defaultBranchPattern+"\"")
+	gitBranchCmd.Flags().String("ticket", "", "任务编号，例如 JIRA-123，默认从描述中识别")
+	gitBranchCmd.Flags().Int("max-length", 60, "分支名的最大长度")
+	gitBranchCmd.Flags().Bool("dry-run", false, "只打印分支名，不创建分支")
+	gitBranchCmd.Flags().StringP("prompt", "t", "", "自定义提示信息，可使用 {{.Description}} {{.Types}}，需要 AI 输出 JSON")
+}
+
+func suggestBranch(cmd *cobra.Command, description string) (branchSuggestion, error) {
+	var suggestion branchSuggestion
+
+	templateStr, err := cmd.Flags().GetString("prompt")
+	if err != nil {
+		return suggestion, fmt.Errorf("获取 prompt 标志失败: %v", err)
+	}
+
+	if templateStr == "" {
+		templateStr = os.Getenv("AICLI_GITBRANCH_PROMPT")
+	}
+
+	if templateStr == "" {
+		templateStr = defaultBranchPrompt
+	}
+
+	tmpl, err := template.New("branch").Parse(templateStr)
+	if err != nil {
+		return suggestion, fmt.Errorf("解析模板失败: %v", err)
+	}
+
+	var promptBuffer bytes.Buffer
+	err = tmpl.Execute(&promptBuffer, struct {
+		Description string
+		Types       string
+	}{
+		Description: description,
+		Types:       strings.Join(branchTypes, "、"),
+	})
+	if err != nil {
+		return suggestion, fmt.Errorf("执行模板失败: %v", err)
+	}
+
+	response, err := provider.GenerateContent(promptBuffer.String())
+	if err != nil {
+		return suggestion, err
+	}
+
+	start := strings.Index(response, "{")
+	end := strings.LastIndex(response, "}")
+	if start < 0 || end < start {
+		return suggestion, fmt.Errorf("回复中没有找到 JSON: %s", response)
+	}
+	if err := json.Unmarshal([]byte(response[start:end+1]), &suggestion); err != nil {
+		return suggestion, fmt.Errorf("解析回复失败: %v", err)
+	}
+
+	suggestion.Type = strings.ToLower(strings.TrimSpace(suggestion.Type))
+	valid := false
+	for _, t := range branchTypes {
+		if t == suggestion.Type {
+			valid = true
+		}
+	}
+	if !valid {
+		suggestion.Type = "feat"
+	}
+	return suggestion, nil
+}
+
+// findTicket 返回描述中的第一个任务编号，跳过 UTF-8 这类技术名词
+func findTicket(description string) string {
+	for _, m := range ticketPattern.FindAllString(description, -1) {
+		if !notTickets[m[:strings.Index(m, "-")]] {
+			return m
+		}
+	}
+	return ""
+}
+
+// normalizeSlug 将 slug 转为小写，非字母数字的部分替换为连字符
+func normalizeSlug(slug string) string {
+	return strings.Trim(slugInvalid.ReplaceAllString(strings.ToLower(slug), "-"), "-")
+}
+
+// renderBranchName 按模板生成分支名，slug 会按最大长度截断，合法性由 Repo.CheckBranchName 检查
+func renderBranchName(pattern, branchType, ticket, slug string, maxLength int) (string, error) {
+	tmpl, err := template.New("branch-name").Parse(pattern)
+	if err != nil {
+		return "", fmt.Errorf("解析分支名模板失败: %v", err)
+	}
+
+	render := func(slug string) (string, error) {
+		var buf bytes.Buffer
+		err := tmpl.Execute(&buf, struct {
+			Type   string
+			Ticket string
+			Slug   string
+		}{
+			Type:   branchType,
+			Ticket: ticket,
+			Slug:   slug,
+		})
+		return buf.String(), err
+	}
+
+	name, err := render(slug)
+	if err != nil {
+		return "", fmt.Errorf("执行分支名模板失败: %v", err)
+	}
+
+	// 超出长度时按单词截短 slug
+	for maxLength > 0 && len(name) > maxLength && strings.Contains(slug, "-") {
+		slug = slug[:strings.LastIndex(slug, "-")]
+		if name, err = render(slug); err != nil {
+			return "", err
+		}
+	}
+
+	return name, nil
+}
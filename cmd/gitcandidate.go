@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/fanook/aicli/internal/commitmsg"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"strconv"
+	"strings"
+)
+
+// commitCandidate 是一条候选 commit 信息及其风格
+type commitCandidate struct {
+	Style   commitmsg.Style
+	Message string
+}
+
+// chooseCommitCandidate 按不同风格生成多条候选 commit 信息，由用户选择、重新生成或编辑。
+// 用户取消时返回 false。
+func chooseCommitCandidate(cmd *cobra.Command, ctx commitContext, n int) (string, bool, error) {
+	prefs, err := commitmsg.LoadPreferences()
+	if err != nil {
+		logrus.Warnf("读取风格偏好失败: %v", err)
+	}
+	styles := prefs.Ordered()
+	conventional, _ := cmd.Flags().GetBool("conventional")
+
+	candidates := make([]commitCandidate, n)
+	generate := func(i int) error {
+		style := styles[i%len(styles)]
+		c := ctx
+		c.Style = style.InstructionFor(conventional)
+		if i >= len(styles) {
+			c.Style += "，并且与其他候选使用不同的措辞"
+		}
+		message, err := generateCommitMessage(cmd, c)
+		if err != nil {
+			return err
+		}
+		candidates[i] = commitCandidate{Style: style, Message: message}
+		return nil
+	}
+
+	for i := range candidates {
+		if err := generate(i); err != nil {
+			return "", false, err
+		}
+	}
+
+	for {
+		fmt.Println("\n候选 commit 信息:")
+		for i, c := range candidates {
+			fmt.Printf("\n[%d] (%s)\n%s\n", i+1, c.Style.Name, indent(c.Message, "    "))
+		}
+
+		input := strings.ToLower(readLine("\n输入编号选择，r 重新生成全部，r<编号> 重新生成单条，e<编号> 编辑后使用，q 取消: "))
+		switch {
+		case input == "q":
+			return "", false, nil
+		case input == "r":
+			for i := range candidates {
+				if err := generate(i); err != nil {
+					return "", false, err
+				}
+			}
+		case strings.HasPrefix(input, "r"):
+			i, ok := candidateIndex(input[1:], n)
+			if !ok {
+				fmt.Println("无效的编号")
+				continue
+			}
+			if err := generate(i); err != nil {
+				return "", false, err
+			}
+		case strings.HasPrefix(input, "e"):
+			i, ok := candidateIndex(input[1:], n)
+			if !ok {
+				fmt.Println("无效的编号")
+				continue
+			}
+			edited, err := editText(candidates[i].Message, "commit_message_*.txt")
+			if err != nil {
+				return "", false, err
+			}
+			recordStyle(prefs, candidates[i].Style)
+			return edited, true, nil
+		default:
+			i, ok := candidateIndex(input, n)
+			if !ok {
+				fmt.Println("无效的输入")
+				continue
+			}
+			recordStyle(prefs, candidates[i].Style)
+			return candidates[i].Message, true, nil
+		}
+	}
+}
+
+func candidateIndex(s string, n int) (int, bool) {
+	i, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil || i < 1 || i > n {
+		return 0, false
+	}
+	return i - 1, true
+}
+
+func recordStyle(prefs commitmsg.Preferences, style commitmsg.Style) {
+	if err := prefs.Record(style.Name); err != nil {
+		logrus.Warnf("保存风格偏好失败: %v", err)
+	}
+}
+
+// indent 为多行文本的每一行添加前缀
+func indent(s, prefix string) string {
+	return prefix + strings.ReplaceAll(s, "\n", "\n"+prefix)
+}
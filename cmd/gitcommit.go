@@ -3,159 +3,323 @@ package cmd
 import (
 	"bytes"
 	"fmt"
+	"github.com/fanook/aicli/internal/commitmsg"
 	"github.com/fanook/aicli/internal/githelper"
 	"github.com/fanook/aicli/internal/provider"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
-	"io/ioutil"
 	"os"
-	"os/exec"
 	"strings"
 	"text/template"
 )
 
+const defaultCommitPrompt = "你是一个帮助生成 Git commit 信息的助手。请根据以下 Git 仓库的变更生成一个简洁且有意义的 Git commit 信息。请严格遵循以下格式，并且只能使用以下两种类别：\n\n[类别] 描述\n\n**可用类别：**\n- **feat**: 新功能\n- **fix**: 修复\n\n**示例：**\n[fix] 修复用户登录时的验证错误\n[feat] 添加用户个人资料页面\n{{if .Language}}\n描述请使用{{.Language}}撰写，类别保持英文。\n{{end}}\n变更内容：\n{{.Changes}}"
+
+const defaultConventionalCommitPrompt = "你是一个帮助生成 Git commit 信息的助手。请根据以下 Git 仓库的变更，生成一条符合 Conventional Commits 规范的 commit 信息，只输出 commit 信息本身。\n\n格式：\n<类型>(<范围>): <描述>\n\n<正文>\n\n<脚注>\n\n要求：\n- 类型只能是 feat、fix、refactor、docs、test、chore、perf、build、ci 之一\n- 范围可选{{if .Scope}}，根据变更文件推断的范围为 {{.Scope}}{{end}}\n- 标题行不超过 72 个字符，描述末尾不加句号\n- 正文说明为什么要做这次修改，而不仅仅是修改了什么\n- 如果包含不兼容的变更，在类型后加 !，并添加 \"BREAKING CHANGE: <说明>\" 脚注\n{{if .Language}}- 描述和正文使用{{.Language}}撰写，类型、范围和 BREAKING CHANGE 关键字保持英文\n{{end}}\n**示例：**\nfix(githelper): 修复重命名文件时路径解析错误\n\n重命名的文件在差异中同时出现新旧路径，之前只取了旧路径，导致\n生成的描述与实际变更不符。\n\n变更内容：\n{{.Changes}}"
+
+// commitContext 保存生成 commit 信息所需的变更信息
+type commitContext struct {
+	Changes string
+	Status  string
+	Diff    string
+	Scope   string
+	// Language 是生成内容使用的语言，为空时使用模板的默认语言
+	Language string
+	// Style 是附加在提示词末尾的风格要求，默认取用户最常选择的风格
+	Style string
+}
+
 // gcCmd 定义了 gc 命令
 var gcCmd = &cobra.Command{
 	Use:     "git-cmt",
 	Short:   "使用Git Changes生成commit 信息",
-	Long:    `根据当前 Git 仓库的变更，使用 AI 生成合适的 Git commit 信息，允许用户编辑后自动执行提交命令。`,
-	Example: `  acl git-cmt`,
+	Long:    `根据当前 Git 仓库暂存区的变更，使用 AI 生成合适的 Git commit 信息，允许用户编辑后自动执行提交命令。`,
+	Example: "  acl git-cmt\n  acl git-cmt --all --conventional",
 	Run: func(cmd *cobra.Command, args []string) {
-		changes, err := githelper.GetGitChanges()
+		stageAll, _ := cmd.Flags().GetBool("all")
+		pick, _ := cmd.Flags().GetBool("pick")
+		commitOpts := commitOptionsFromFlags(cmd)
+
+		repo, err := githelper.Open("")
 		if err != nil {
-			logrus.Fatalf("获取 Git 变更信息失败: %v", err)
+			logrus.Fatal(err)
 		}
 
-		if changes == "" {
-			logrus.Info("当前没有任何变更，无需提交。")
+		if split, _ := cmd.Flags().GetBool("split"); split {
+			if commitOpts.Amend {
+				logrus.Fatal("--split 不能与 --amend 同时使用：每组提交都会修订同一个提交，最终合并为一个")
+			}
+			runSplit(cmd, repo, commitOpts)
 			return
 		}
 
-		logrus.Infof("当前变更:\n%s", changes)
-
-		apiKey := os.Getenv("AICLI_OPENAI_API_KEY")
-		if apiKey == "" {
-			logrus.Fatal("未设置 AICLI_OPENAI_API_KEY 环境变量")
+		if stageAll {
+			if err := repo.StageTracked(); err != nil {
+				logrus.Fatalf("暂存已跟踪文件失败: %v", err)
+			}
 		}
 
-		templateStr, err := cmd.Flags().GetString("prompt")
-		if err != nil {
-			logrus.Fatalf("获取 template 标志失败: %v", err)
+		if pick {
+			if err := pickAndStage(repo); err != nil {
+				logrus.Fatalf("选择文件失败: %v", err)
+			}
 		}
 
-		if templateStr == "" {
-			templateStr = os.Getenv("AICLI_GITCOMMIT_PROMPT")
+		files, err := stagedDiff(cmd, repo, commitOpts.Amend)
+		if err != nil {
+			logrus.Fatalf("获取 Git 差异失败: %v", err)
 		}
 
-		if templateStr == "" {
-			templateStr = "你是一个帮助生成 Git commit 信息的助手。请根据以下 Git 仓库的变更生成一个简洁且有意义的 Git commit 信息。请严格遵循以下格式，并且只能使用以下两种类别：\n\n[类别] 描述\n\n**可用类别：**\n- **feat**: 新功能\n- **fix**: 修复\n\n**示例：**\n[fix] 修复用户登录时的验证错误\n[feat] 添加用户个人资料页面\n\n变更内容：\n{{.Changes}}"
+		if len(files) == 0 {
+			unstaged, err := repo.UnstagedFiles()
+			if err != nil {
+				logrus.Fatalf("获取 Git 变更信息失败: %v", err)
+			}
+			if len(unstaged) == 0 {
+				logrus.Info("当前没有任何变更，无需提交。")
+				return
+			}
+			logrus.Fatal("暂存区为空，拒绝提交。请先使用 git add 暂存文件，或使用 --all 暂存已跟踪文件的变更、--pick 交互式选择文件。")
 		}
 
-		tmpl, err := template.New("commit").Parse(templateStr)
+		ctx, err := newCommitContext(cmd, repo, files)
 		if err != nil {
-			logrus.Fatalf("解析模板失败: %v", err)
+			logrus.Fatal(err)
 		}
+		logrus.Infof("待提交的变更:\n%s", ctx.Status)
 
-		var promptBuffer bytes.Buffer
-		err = tmpl.Execute(&promptBuffer, struct {
-			Changes string
-		}{
-			Changes: changes,
-		})
-		if err != nil {
-			logrus.Fatalf("执行模板失败: %v", err)
+		apiKey := os.Getenv("AICLI_OPENAI_API_KEY")
+		if apiKey == "" {
+			logrus.Fatal("未设置 AICLI_OPENAI_API_KEY 环境变量")
 		}
 
-		prompt := promptBuffer.String()
-
-		commitMessage, err := provider.GenerateContent(prompt)
-		if err != nil {
-			logrus.Fatalf("生成 commit 信息失败: %v", err)
-		}
+		var finalCommitMessage string
+		candidates, _ := cmd.Flags().GetInt("candidates")
+		if candidates > 1 {
+			var ok bool
+			finalCommitMessage, ok, err = chooseCommitCandidate(cmd, ctx, candidates)
+			if err != nil {
+				logrus.Fatalf("生成 commit 信息失败: %v", err)
+			}
+			if !ok {
+				logrus.Info("操作已取消。")
+				return
+			}
+		} else {
+			commitMessage, err := generateCommitMessage(cmd, ctx)
+			if err != nil {
+				logrus.Fatalf("生成 commit 信息失败: %v", err)
+			}
 
-		fmt.Printf("\n生成的 commit 信息:\n%s\n\n", commitMessage)
+			fmt.Printf("\n生成的 commit 信息:\n%s\n\n", commitMessage)
 
-		tmpFile, err := ioutil.TempFile("", "commit_message_*.txt")
-		if err != nil {
-			logrus.Fatalf("创建临时文件失败: %v", err)
+			finalCommitMessage, err = editText(commitMessage, "commit_message_*.txt")
+			if err != nil {
+				logrus.Fatal(err)
+			}
 		}
-		defer os.Remove(tmpFile.Name()) // 程序结束时删除临时文件
 
-		// 写入初始 commit 信息
-		_, err = tmpFile.WriteString(commitMessage)
-		if err != nil {
-			logrus.Fatalf("写入临时文件失败: %v", err)
-		}
-		tmpFile.Close()
-
-		editor := os.Getenv("EDITOR")
-		if editor == "" {
-			// 如果未设置 EDITOR 环境变量，使用默认的编辑器
-			if isCommandAvailable("vim") {
-				editor = "vim"
-			} else if isCommandAvailable("nano") {
-				editor = "nano"
-			} else {
-				logrus.Fatal("未设置 EDITOR 环境变量，且系统中未安装 vim 或 nano。")
-			}
+		if finalCommitMessage == "" {
+			logrus.Fatal("Commit 信息为空，取消提交。")
 		}
 
-		cmdEditor := exec.Command(editor, tmpFile.Name())
-		cmdEditor.Stdin = os.Stdin
-		cmdEditor.Stdout = os.Stdout
-		cmdEditor.Stderr = os.Stderr
+		fmt.Printf("最终的 commit 信息:\n%s\n\n", finalCommitMessage)
 
-		if err := cmdEditor.Run(); err != nil {
-			logrus.Fatalf("打开编辑器失败: %v", err)
+		if !confirm("确认提交？") {
+			logrus.Info("操作已取消。")
+			return
 		}
 
-		finalCommitBytes, err := ioutil.ReadFile(tmpFile.Name())
+		hash, err := repo.Commit(finalCommitMessage, commitOpts)
 		if err != nil {
-			logrus.Fatalf("读取临时文件失败: %v", err)
+			logrus.Fatalf("提交失败: %v", err)
 		}
+		logrus.Infof("成功。提交: %s", hash[:7])
+	},
+}
 
-		finalCommitMessage := strings.TrimSpace(string(finalCommitBytes))
+func init() {
+	rootCmd.AddCommand(gcCmd)
+	gcCmd.Flags().String("author", "", "指定提交作者，格式为 \"Name <email>\"")
+	gcCmd.Flags().BoolP("signoff", "s", false, "添加 Signed-off-by 脚注")
+	gcCmd.Flags().BoolP("gpg-sign", "S", false, "使用 GPG 签名提交")
+	gcCmd.Flags().String("gpg-key", "", "指定 GPG 签名使用的密钥，隐含 --gpg-sign")
+	gcCmd.Flags().Bool("amend", false, "修订上一次提交，根据上一次提交及新暂存的变更重新生成 commit 信息")
+	gcCmd.PersistentFlags().StringP("prompt", "t", "", "自定义生成commit的提示信息，例如: --prompt \"[fix] {{.Changes}}\"")
+	gcCmd.Flags().BoolP("all", "a", false, "提交前暂存所有已跟踪文件的变更（不包含未跟踪文件）")
+	gcCmd.Flags().Bool("split", false, "将工作区的变更按文件拆分为多个逻辑提交，此时 --prompt 可使用 {{.Format}} {{.Files}} {{.Changes}} {{.Language}}")
+	gcCmd.Flags().IntP("candidates", "n", 1, "生成多个不同风格的候选 commit 信息供选择")
+	gcCmd.Flags().BoolP("pick", "i", false, "交互式选择需要暂存的文件")
+	gcCmd.PersistentFlags().Int("max-tokens", 6000, "发送给 AI 的差异内容的 token 预算，超出部分仅保留文件摘要")
+	gcCmd.PersistentFlags().Int("max-file-lines", 200, "单个文件保留的最大差异行数")
+	gcCmd.PersistentFlags().BoolP("conventional", "c", false, "生成符合 Conventional Commits 规范的 commit 信息")
+	gcCmd.PersistentFlags().Bool("allow-secrets", false, "检测到敏感信息时仍然发送（敏感内容会被屏蔽）")
+	gcCmd.PersistentFlags().String("lang", "", "生成内容使用的语言，如 zh、en，默认读取仓库 .aicli.yaml 中的 lang 或 AICLI_LANG")
+	gcCmd.PersistentFlags().Int("max-retries", 3, "Conventional Commits 模式下输出不合规时重新生成的最大次数")
+}
 
-		if finalCommitMessage == "" {
-			logrus.Fatal("Commit 信息为空，取消提交。")
+// emptyTreeHash 是 Git 中空树对象的哈希，用于与根提交比较
+const emptyTreeHash = "4b825dc642cb6eb9a060e54bf8d69288fbee4904"
+
+// stagedDiff 按命令行参数收集暂存区差异。修订提交时与上一次提交的父提交比较，
+// 使生成的信息覆盖被修订的提交及新暂存的变更。
+func stagedDiff(cmd *cobra.Command, repo *githelper.Repo, amend bool) ([]githelper.FileDiff, error) {
+	maxTokens, _ := cmd.Flags().GetInt("max-tokens")
+	maxFileLines, _ := cmd.Flags().GetInt("max-file-lines")
+	opts := githelper.DiffOptions{MaxFileLines: maxFileLines, TokenBudget: maxTokens}
+	if amend {
+		opts.Base = "HEAD~1"
+		if _, err := repo.RevParse("HEAD~1"); err != nil {
+			opts.Base = emptyTreeHash
 		}
+	}
+	return repo.StagedDiff(opts)
+}
 
-		fmt.Printf("最终的 commit 信息:\n%s\n\n", finalCommitMessage)
+// commitOptionsFromFlags 从命令行参数读取提交选项
+func commitOptionsFromFlags(cmd *cobra.Command) githelper.CommitOptions {
+	author, _ := cmd.Flags().GetString("author")
+	signOff, _ := cmd.Flags().GetBool("signoff")
+	gpgSign, _ := cmd.Flags().GetBool("gpg-sign")
+	gpgKey, _ := cmd.Flags().GetString("gpg-key")
+	amend, _ := cmd.Flags().GetBool("amend")
+	return githelper.CommitOptions{
+		Author:  author,
+		SignOff: signOff,
+		GPGSign: gpgSign || gpgKey != "",
+		GPGKey:  gpgKey,
+		Amend:   amend,
+	}
+}
 
-		fmt.Print("确认提交？[y/n]: ")
+// newCommitContext 根据暂存区差异构造提示词所需的变更信息，差异内容会先经过敏感信息检测
+func newCommitContext(cmd *cobra.Command, repo *githelper.Repo, files []githelper.FileDiff) (commitContext, error) {
+	var status strings.Builder
+	var paths []string
+	for _, f := range files {
+		status.WriteString(fmt.Sprintf("%s\t%s\n", f.Status, f.Path))
+		paths = append(paths, f.Path)
+	}
+	guarded, err := guardSecrets(cmd, githelper.FormatDiff(files))
+	if err != nil {
+		return commitContext{}, err
+	}
+	diff := guarded[0]
 
-		// 捕捉用户输入
-		var userInput string
-		fmt.Scanln(&userInput)
+	ctx := commitContext{
+		Changes:  diff,
+		Status:   strings.TrimSpace(status.String()),
+		Diff:     diff,
+		Scope:    commitmsg.InferScope(paths),
+		Language: outputLanguage(cmd, repo),
+	}
 
-		userInput = strings.ToLower(strings.TrimSpace(userInput))
+	prefs, err := commitmsg.LoadPreferences()
+	if err != nil {
+		logrus.Warnf("读取风格偏好失败: %v", err)
+	}
+	if style, ok := prefs.Favorite(); ok {
+		conventional, _ := cmd.Flags().GetBool("conventional")
+		ctx.Style = style.InstructionFor(conventional)
+	}
+	return ctx, nil
+}
 
-		if userInput != "y" && userInput != "Y" {
-			logrus.Info("操作已取消。")
-			return
+// generateCommitMessage 渲染提示词并调用 AI 生成 commit 信息。
+// Conventional Commits 模式下会校验输出，不合规时附带错误原因重新生成。
+func generateCommitMessage(cmd *cobra.Command, ctx commitContext) (string, error) {
+	conventional, _ := cmd.Flags().GetBool("conventional")
+	maxRetries, _ := cmd.Flags().GetInt("max-retries")
+
+	templateStr, err := cmd.Flags().GetString("prompt")
+	if err != nil {
+		return "", fmt.Errorf("获取 template 标志失败: %v", err)
+	}
+
+	if templateStr == "" && conventional {
+		templateStr = os.Getenv("AICLI_GITCOMMIT_CONVENTIONAL_PROMPT")
+		if templateStr == "" {
+			templateStr = defaultConventionalCommitPrompt
 		}
+	}
 
-		addCmd := exec.Command("git", "add", ".")
-		addOutput, err := addCmd.CombinedOutput()
-		if err != nil {
-			logrus.Fatalf("执行 'git add .' 失败: %v\n输出: %s", err, string(addOutput))
+	if templateStr == "" {
+		templateStr = os.Getenv("AICLI_GITCOMMIT_PROMPT")
+	}
+
+	if templateStr == "" {
+		templateStr = defaultCommitPrompt
+	}
+
+	tmpl, err := template.New("commit").Parse(templateStr)
+	if err != nil {
+		return "", fmt.Errorf("解析模板失败: %v", err)
+	}
+
+	var promptBuffer bytes.Buffer
+	err = tmpl.Execute(&promptBuffer, ctx)
+	if err != nil {
+		return "", fmt.Errorf("执行模板失败: %v", err)
+	}
+
+	prompt := withLanguage(promptBuffer.String(), templateStr, ctx.Language)
+	if ctx.Style != "" {
+		prompt += "\n\n风格要求：" + ctx.Style
+	}
+
+	commitMessage, err := provider.GenerateContent(prompt)
+	if err != nil {
+		return "", err
+	}
+
+	if !conventional {
+		return commitMessage, nil
+	}
+
+	commitMessage = commitmsg.Normalize(commitMessage)
+	for attempt := 1; ; attempt++ {
+		verr := commitmsg.ValidateConventional(commitMessage)
+		if verr == nil {
+			return commitMessage, nil
+		}
+		if attempt > maxRetries {
+			logrus.Warnf("多次生成仍不符合 Conventional Commits 规范，请在编辑器中手动修正: %v", verr)
+			return commitMessage, nil
 		}
 
-		commitCmd := exec.Command("git", "commit", "-m", finalCommitMessage)
-		commitOutput, err := commitCmd.CombinedOutput()
+		logrus.Warnf("生成的 commit 信息不符合规范，正在重新生成 (%d/%d): %v", attempt, maxRetries, verr)
+		retryPrompt := fmt.Sprintf("%s\n\n你上一次的输出是:\n%s\n\n它不符合规范: %v\n请修正后重新输出完整的 commit 信息。", prompt, commitMessage, verr)
+		commitMessage, err = provider.GenerateContent(retryPrompt)
 		if err != nil {
-			logrus.Fatalf("执行 'git commit' 失败: %v\n输出: %s", err, string(commitOutput))
+			return "", err
 		}
-		logrus.Infof("成功。")
-	},
+		commitMessage = commitmsg.Normalize(commitMessage)
+	}
 }
 
-func init() {
-	rootCmd.AddCommand(gcCmd)
-	gcCmd.Flags().StringP("prompt", "t", "", "自定义生成commit的提示信息，例如: --prompt \"[fix] {{.Changes}}\"")
-}
+// pickAndStage 列出未暂存的文件，由用户选择需要暂存的文件
+func pickAndStage(repo *githelper.Repo) error {
+	files, err := repo.UnstagedFiles()
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		logrus.Info("没有可供选择的未暂存文件。")
+		return nil
+	}
+
+	for i, f := range files {
+		fmt.Printf("%3d) %-2s %s\n", i+1, f.Status, f.Path)
+	}
+
+	selected, err := parseSelection(readLine("请选择要暂存的文件编号（如 1 3 5-7，a 表示全部，回车跳过）: "), len(files))
+	if err != nil {
+		return err
+	}
 
-func isCommandAvailable(name string) bool {
-	_, err := exec.LookPath(name)
-	return err == nil
+	var paths []string
+	for _, i := range selected {
+		paths = append(paths, files[i].Path)
+	}
+	return repo.StagePaths(paths)
 }
@@ -0,0 +1,232 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/fanook/aicli/internal/githelper"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// hookMarker 用于识别由 aicli 生成的 hook 文件
+const hookMarker = "# aicli prepare-commit-msg hook"
+
+// scissorsLine 是 git commit -v 插入的分隔线，其后的差异内容不属于 commit 信息
+const scissorsLine = "# ------------------------ >8 ------------------------"
+
+const hookName = "prepare-commit-msg"
+
+var gcHookCmd = &cobra.Command{
+	Use:   "hook",
+	Short: "管理 prepare-commit-msg hook",
+	Long:  `安装或卸载 prepare-commit-msg hook，使 git commit 时自动使用 AI 预填 commit 信息。`,
+	Example: `  acl git-cmt hook install
+  acl git-cmt hook install --conventional
+  acl git-cmt hook uninstall`,
+}
+
+var gcHookInstallCmd = &cobra.Command{
+	Use:   "install",
+	Short: "安装 prepare-commit-msg hook",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		force, _ := cmd.Flags().GetBool("force")
+
+		repo, err := githelper.Open("")
+		if err != nil {
+			logrus.Fatal(err)
+		}
+
+		hookPath, err := repo.GitPath(filepath.Join("hooks", hookName))
+		if err != nil {
+			logrus.Fatalf("定位 hooks 目录失败，请确认当前位于 Git 仓库中: %v", err)
+		}
+
+		if existing, err := ioutil.ReadFile(hookPath); err == nil && !strings.Contains(string(existing), hookMarker) {
+			if !force {
+				logrus.Fatalf("%s 已存在且不是由 aicli 生成，使用 --force 覆盖（原文件会备份为 .bak）", hookPath)
+			}
+			if err := os.Rename(hookPath, hookPath+".bak"); err != nil {
+				logrus.Fatalf("备份原有 hook 失败: %v", err)
+			}
+		}
+
+		if err := os.MkdirAll(filepath.Dir(hookPath), 0755); err != nil {
+			logrus.Fatalf("创建 hooks 目录失败: %v", err)
+		}
+
+		if err := ioutil.WriteFile(hookPath, []byte(hookScript(cmd)), 0755); err != nil {
+			logrus.Fatalf("写入 hook 失败: %v", err)
+		}
+		logrus.Infof("已安装 hook: %s", hookPath)
+	},
+}
+
+var gcHookUninstallCmd = &cobra.Command{
+	Use:   "uninstall",
+	Short: "卸载 prepare-commit-msg hook",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		repo, err := githelper.Open("")
+		if err != nil {
+			logrus.Fatal(err)
+		}
+
+		hookPath, err := repo.GitPath(filepath.Join("hooks", hookName))
+		if err != nil {
+			logrus.Fatalf("定位 hooks 目录失败，请确认当前位于 Git 仓库中: %v", err)
+		}
+
+		existing, err := ioutil.ReadFile(hookPath)
+		if os.IsNotExist(err) {
+			logrus.Info("未安装 hook，无需卸载。")
+			return
+		}
+		if err != nil {
+			logrus.Fatalf("读取 hook 失败: %v", err)
+		}
+		if !strings.Contains(string(existing), hookMarker) {
+			logrus.Fatalf("%s 不是由 aicli 生成，拒绝删除。", hookPath)
+		}
+
+		if err := os.Remove(hookPath); err != nil {
+			logrus.Fatalf("删除 hook 失败: %v", err)
+		}
+		if _, err := os.Stat(hookPath + ".bak"); err == nil {
+			if err := os.Rename(hookPath+".bak", hookPath); err != nil {
+				logrus.Fatalf("恢复原有 hook 失败: %v", err)
+			}
+			logrus.Infof("已卸载 hook，并恢复原有的 %s", hookPath)
+			return
+		}
+		logrus.Infof("已卸载 hook: %s", hookPath)
+	},
+}
+
+// gcHookRunCmd 由 hook 脚本调用。任何失败都只输出警告并保持 commit 信息不变，不阻断提交。
+var gcHookRunCmd = &cobra.Command{
+	Use:    "run <msg-file> [source] [sha]",
+	Short:  "由 prepare-commit-msg hook 调用，预填 commit 信息",
+	Hidden: true,
+	Args:   cobra.RangeArgs(1, 3),
+	Run: func(cmd *cobra.Command, args []string) {
+		msgFile := args[0]
+		source := ""
+		if len(args) > 1 {
+			source = args[1]
+		}
+
+		// merge、squash、amend、-m/-F 以及模板都已提供 commit 信息，无需生成
+		if source != "" {
+			return
+		}
+		repo, err := githelper.Open("")
+		if err != nil || repo.InRebase() {
+			return
+		}
+
+		existing, err := ioutil.ReadFile(msgFile)
+		if err != nil {
+			logrus.Warnf("aicli: 读取 commit 信息文件失败: %v", err)
+			return
+		}
+		if hasMessage(string(existing)) {
+			return
+		}
+
+		files, err := stagedDiff(cmd, repo, false)
+		if err != nil || len(files) == 0 {
+			return
+		}
+
+		ctx, err := newCommitContext(cmd, repo, files)
+		if err != nil {
+			logrus.Warnf("aicli: %v", err)
+			return
+		}
+
+		// 提供商不可达时 HTTP 请求可能一直挂起，超时后直接返回，进程退出时未完成的请求随之结束
+		timeout, _ := cmd.Flags().GetDuration("timeout")
+		type result struct {
+			message string
+			err     error
+		}
+		done := make(chan result, 1)
+		go func() {
+			message, err := generateCommitMessage(cmd, ctx)
+			done <- result{message, err}
+		}()
+
+		var message string
+		select {
+		case r := <-done:
+			if r.err != nil {
+				logrus.Warnf("aicli: 生成 commit 信息失败，已跳过: %v", r.err)
+				return
+			}
+			message = r.message
+		case <-time.After(timeout):
+			logrus.Warnf("aicli: 生成 commit 信息超过 %s，已跳过", timeout)
+			return
+		}
+
+		if err := ioutil.WriteFile(msgFile, []byte(message+"\n"+string(existing)), 0644); err != nil {
+			logrus.Warnf("aicli: 写入 commit 信息失败: %v", err)
+		}
+	},
+}
+
+func init() {
+	gcCmd.AddCommand(gcHookCmd)
+	gcHookCmd.AddCommand(gcHookInstallCmd, gcHookUninstallCmd, gcHookRunCmd)
+	gcHookInstallCmd.Flags().BoolP("force", "f", false, "覆盖已存在的非 aicli hook")
+	for _, c := range []*cobra.Command{gcHookInstallCmd, gcHookRunCmd} {
+		c.Flags().Duration("timeout", 20*time.Second, "生成 commit 信息的超时时间，超时后保持 commit 信息为空，不影响提交")
+	}
+}
+
+// hookScript 生成 hook 脚本，安装时指定的生成参数会写入脚本
+func hookScript(cmd *cobra.Command) string {
+	bin, err := os.Executable()
+	if err != nil {
+		bin = "aicli"
+	}
+
+	var flags []string
+	for _, name := range []string{"prompt", "max-tokens", "max-file-lines", "conventional", "max-retries", "allow-secrets", "timeout", "lang"} {
+		if f := cmd.Flags().Lookup(name); f != nil && f.Changed {
+			flags = append(flags, fmt.Sprintf("--%s=%s", name, shellQuote(f.Value.String())))
+		}
+	}
+
+	return fmt.Sprintf(`#!/bin/sh
+%s
+# 由 aicli git-cmt hook install 生成，使用 aicli git-cmt hook uninstall 卸载
+# AI 提供商不可用时保持 commit 信息为空，不影响提交
+%s git-cmt hook run %s"$@" </dev/null || true
+exit 0
+`, hookMarker, shellQuote(bin), strings.Join(append(flags, ""), " "))
+}
+
+// hasMessage 判断 commit 信息文件中是否已有非注释内容，git commit -v 分隔线之后的差异不计入
+func hasMessage(content string) bool {
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == scissorsLine {
+			return false
+		}
+		if line != "" && !strings.HasPrefix(line, "#") {
+			return true
+		}
+	}
+	return false
+}
+
+// shellQuote 使用单引号转义字符串，供 sh 脚本使用
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,22 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHookScriptFlags(t *testing.T) {
+	cmd := gcHookInstallCmd
+	if err := cmd.ParseFlags([]string{"--lang=en", "--conventional", "--timeout=5s"}); err != nil {
+		t.Fatal(err)
+	}
+	script := hookScript(cmd)
+	for _, want := range []string{"--lang='en'", "--conventional='true'", "--timeout='5s'"} {
+		if !strings.Contains(script, want) {
+			t.Errorf("hookScript() missing %s:\n%s", want, script)
+		}
+	}
+	if strings.Contains(script, "--max-tokens") {
+		t.Errorf("hookScript() should only include changed flags:\n%s", script)
+	}
+}
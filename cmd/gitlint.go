@@ -0,0 +1,208 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/fanook/aicli/internal/commitmsg"
+	"github.com/fanook/aicli/internal/githelper"
+	"github.com/fanook/aicli/internal/provider"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"io/ioutil"
+	"os"
+	"strings"
+	"text/template"
+)
+
+const defaultLintPrompt = "你是一个帮助规范 Git commit 信息的助手。以下 commit 信息不符合团队规范，请在保留原意的前提下重写，只输出重写后的 commit 信息。\n\n规范：{{.Format}}\n{{if .Language}}\n描述请使用{{.Language}}撰写。\n{{end}}\n存在的问题：{{.Problems}}\n\n原 commit 信息：\n{{.Message}}\n{{if .Changes}}\n对应的变更：\n{{.Changes}}\n{{end}}"
+
+// lintTarget 是待检查的一条 commit 信息
+type lintTarget struct {
+	Name    string
+	Message string
+	Hash    string
+}
+
+var gcLintCmd = &cobra.Command{
+	Use:   "lint [message]",
+	Short: "检查 commit 信息是否符合团队规范",
+	Long: `按配置的提交规范检查指定的 commit 信息、文件或最近 N 次提交，对不符合规范的信息使用 AI 给出重写建议。
+规范由 --convention 或 AICLI_COMMIT_CONVENTION 指定：bracket（默认，"[feat] 描述"）或 conventional。
+存在不符合规范的信息时以非零状态码退出，可用于 commit-msg hook。`,
+	Example: `  acl git-cmt lint "修复登录问题"
+  acl git-cmt lint -n 10
+  acl git-cmt lint --file .git/COMMIT_EDITMSG --convention conventional`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		convention := lintConvention(cmd)
+		last, _ := cmd.Flags().GetInt("last")
+		file, _ := cmd.Flags().GetString("file")
+
+		var targets []lintTarget
+		var repo *githelper.Repo
+		switch {
+		case len(args) == 1:
+			targets = append(targets, lintTarget{Name: "参数", Message: args[0]})
+		case file != "":
+			content, err := ioutil.ReadFile(file)
+			if err != nil {
+				logrus.Fatalf("读取文件失败: %v", err)
+			}
+			targets = append(targets, lintTarget{Name: file, Message: stripComments(string(content))})
+		default:
+			var err error
+			repo, err = githelper.Open("")
+			if err != nil {
+				logrus.Fatal(err)
+			}
+			if last <= 0 {
+				last = 1
+			}
+			commits, err := repo.Log(githelper.LogOptions{MaxCount: last, NoMerges: true})
+			if err != nil {
+				logrus.Fatalf("获取提交记录失败: %v", err)
+			}
+			for _, c := range commits {
+				message := c.Subject
+				if c.Body != "" {
+					message += "\n\n" + c.Body
+				}
+				targets = append(targets, lintTarget{Name: c.ShortHash(), Message: message, Hash: c.Hash})
+			}
+		}
+
+		noSuggest, _ := cmd.Flags().GetBool("no-suggest")
+		failed := 0
+		for _, t := range targets {
+			verr := commitmsg.Validate(t.Message, convention)
+			if verr == nil {
+				fmt.Printf("✔ %s: %s\n", t.Name, firstLine(t.Message))
+				continue
+			}
+
+			failed++
+			fmt.Printf("✘ %s: %s\n    %v\n", t.Name, firstLine(t.Message), verr)
+			if noSuggest {
+				continue
+			}
+
+			suggestion, err := suggestRewrite(cmd, repo, t, convention, verr)
+			if err != nil {
+				logrus.Errorf("生成重写建议失败: %v", err)
+				continue
+			}
+			fmt.Printf("    建议改为:\n%s\n", indent(suggestion, "        "))
+		}
+
+		if failed > 0 {
+			fmt.Printf("\n%d/%d 条 commit 信息不符合 %s 规范。\n", failed, len(targets), convention)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	gcCmd.AddCommand(gcLintCmd)
+	gcLintCmd.Flags().IntP("last", "n", 1, "检查最近 N 次提交")
+	gcLintCmd.Flags().StringP("file", "F", "", "检查文件中的 commit 信息，例如 commit-msg hook 传入的文件")
+	gcLintCmd.Flags().String("convention", "", "提交规范：bracket 或 conventional，默认读取 AICLI_COMMIT_CONVENTION")
+	gcLintCmd.Flags().Bool("no-suggest", false, "只检查，不生成重写建议")
+}
+
+// lintConvention 确定检查使用的规范，--conventional 等价于 --convention conventional
+func lintConvention(cmd *cobra.Command) string {
+	convention, _ := cmd.Flags().GetString("convention")
+	if conventional, _ := cmd.Flags().GetBool("conventional"); conventional {
+		convention = commitmsg.ConventionConventional
+	}
+	if convention == "" {
+		convention = os.Getenv("AICLI_COMMIT_CONVENTION")
+	}
+	if convention != commitmsg.ConventionConventional {
+		convention = commitmsg.ConventionBracket
+	}
+	return convention
+}
+
+func suggestRewrite(cmd *cobra.Command, repo *githelper.Repo, t lintTarget, convention string, verr error) (string, error) {
+	format := bracketFormat
+	if convention == commitmsg.ConventionConventional {
+		format = conventionalFormat
+	}
+
+	changes := ""
+	if repo != nil && t.Hash != "" {
+		files, err := repo.Diff(githelper.DiffOptions{Base: t.Hash + "^!", MaxFileLines: 50, TokenBudget: 2000})
+		if err == nil {
+			guarded, err := guardSecrets(cmd, githelper.FormatDiff(files))
+			if err != nil {
+				return "", err
+			}
+			changes = guarded[0]
+		}
+	}
+
+	templateStr, err := cmd.Flags().GetString("prompt")
+	if err != nil {
+		return "", fmt.Errorf("获取 prompt 标志失败: %v", err)
+	}
+
+	if templateStr == "" {
+		templateStr = os.Getenv("AICLI_GITLINT_PROMPT")
+	}
+
+	if templateStr == "" {
+		templateStr = defaultLintPrompt
+	}
+
+	tmpl, err := template.New("lint").Parse(templateStr)
+	if err != nil {
+		return "", fmt.Errorf("解析模板失败: %v", err)
+	}
+
+	language := outputLanguage(cmd, repo)
+	var promptBuffer bytes.Buffer
+	err = tmpl.Execute(&promptBuffer, struct {
+		Format   string
+		Problems string
+		Message  string
+		Changes  string
+		Language string
+	}{
+		Format:   format,
+		Problems: verr.Error(),
+		Message:  t.Message,
+		Changes:  changes,
+		Language: language,
+	})
+	if err != nil {
+		return "", fmt.Errorf("执行模板失败: %v", err)
+	}
+
+	suggestion, err := provider.GenerateContent(withLanguage(promptBuffer.String(), templateStr, language))
+	if err != nil {
+		return "", err
+	}
+	if convention == commitmsg.ConventionConventional {
+		suggestion = commitmsg.Normalize(suggestion)
+	}
+	return suggestion, nil
+}
+
+// stripComments 去掉 commit 信息文件中以 # 开头的注释行，git commit -v 分隔线之后的内容全部忽略
+func stripComments(content string) string {
+	var lines []string
+	for _, line := range strings.Split(content, "\n") {
+		if strings.TrimRight(line, "\r") == scissorsLine {
+			break
+		}
+		if !strings.HasPrefix(line, "#") {
+			lines = append(lines, line)
+		}
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+func firstLine(s string) string {
+	return strings.SplitN(strings.TrimSpace(s), "\n", 2)[0]
+}
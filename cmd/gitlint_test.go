@@ -0,0 +1,25 @@
+package cmd
+
+import "testing"
+
+func TestStripComments(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"plain", "[feat] add\n\nbody\n", "[feat] add\n\nbody"},
+		{"comments", "[feat] add\n# Please enter the commit message\n#\n", "[feat] add"},
+		{
+			name:    "verbose diff after scissors",
+			content: "[fix] x\n\n" + scissorsLine + "\n# Do not modify or remove the line above.\ndiff --git a/a b/a\n+added line\n",
+			want:    "[fix] x",
+		},
+		{"crlf scissors", "[fix] x\r\n" + scissorsLine + "\r\n+added\r\n", "[fix] x"},
+	}
+	for _, tt := range tests {
+		if got := stripComments(tt.content); got != tt.want {
+			t.Errorf("%s: stripComments() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
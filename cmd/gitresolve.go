@@ -0,0 +1,279 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/fanook/aicli/internal/conflict"
+	"github.com/fanook/aicli/internal/githelper"
+	"github.com/fanook/aicli/internal/provider"
+	"github.com/fanook/aicli/internal/secrets"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+const defaultResolvePrompt = "你是一个帮助解决 Git 合并冲突的助手。请根据冲突双方的内容{{if .HasBase}}以及共同祖先（base）的内容{{end}}，合并两边的意图，给出解决后的代码。\n\n要求：\n- resolution 中只包含用于替换整个冲突区域（含冲突标记）的代码，不要包含上下文，不要包含冲突标记\n- 保持原有的缩进风格\n- 只输出 JSON，不要输出其他内容，格式如下：\n{\"resolution\": \"解决后的代码\", \"explanation\": \"简要说明如何合并以及原因\"}\n\n文件：{{.File}}\n\n冲突前的上下文：\n{{.Before}}\nours（{{.OursLabel}}）：\n{{.Ours}}\n{{if .HasBase}}base：\n{{.Base}}\n{{end}}theirs（{{.TheirsLabel}}）：\n{{.Theirs}}\n冲突后的上下文：\n{{.After}}"
+
+// resolution 是 AI 给出的冲突解决方案
+type resolution struct {
+	Resolution  string `json:"resolution"`
+	Explanation string `json:"explanation"`
+}
+
+var gitResolveCmd = &cobra.Command{
+	Use:   "git-resolve [file...]",
+	Short: "使用 AI 辅助解决合并冲突",
+	Long: `查找存在冲突的文件，将每处冲突的 ours、theirs、base 及上下文发送给 AI 生成解决方案，
+逐处确认接受、编辑或跳过，写回文件后对已完全解决的文件执行 git add。`,
+	Example: `  acl git-resolve
+  acl git-resolve --diff3 src/main.go`,
+	Run: func(cmd *cobra.Command, args []string) {
+		repo, err := githelper.Open("")
+		if err != nil {
+			logrus.Fatal(err)
+		}
+
+		files, err := repo.ConflictedFiles()
+		if err != nil {
+			logrus.Fatalf("获取冲突文件失败: %v", err)
+		}
+		if len(args) > 0 {
+			files = filterPaths(repo, files, args)
+		}
+		if len(files) == 0 {
+			logrus.Info("没有存在冲突的文件。")
+			return
+		}
+
+		tmpl, err := resolveTemplate(cmd)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+
+		diff3, _ := cmd.Flags().GetBool("diff3")
+		contextLines, _ := cmd.Flags().GetInt("context")
+
+		for i, path := range files {
+			fmt.Printf("\n===== [%d/%d] %s =====\n", i+1, len(files), path)
+
+			if diff3 {
+				if err := repo.RestoreConflictDiff3(path); err != nil {
+					logrus.Errorf("以 diff3 风格重新生成冲突失败: %v", err)
+				}
+			}
+
+			fullPath := filepath.Join(repo.Dir, path)
+			content, err := ioutil.ReadFile(fullPath)
+			if err != nil {
+				logrus.Errorf("读取 %s 失败: %v", path, err)
+				continue
+			}
+
+			parsed, err := conflict.Parse(string(content), contextLines)
+			if err != nil {
+				logrus.Errorf("解析 %s 失败: %v", path, err)
+				continue
+			}
+
+			hunks := parsed.Hunks()
+			if len(hunks) == 0 {
+				logrus.Infof("%s 中没有冲突标记，可能已手动解决。", path)
+				continue
+			}
+
+			quit := false
+			for j, h := range hunks {
+				fmt.Printf("\n--- 冲突 %d/%d ---\n", j+1, len(hunks))
+				if !resolveHunk(cmd, tmpl, path, h) {
+					quit = true
+					break
+				}
+			}
+
+			if err := ioutil.WriteFile(fullPath, []byte(parsed.String()), 0644); err != nil {
+				logrus.Fatalf("写入 %s 失败: %v", path, err)
+			}
+
+			if n := parsed.Unresolved(); n > 0 {
+				logrus.Infof("%s 还有 %d 处冲突未解决。", path, n)
+			} else if err := repo.StagePaths([]string{path}); err != nil {
+				logrus.Errorf("git add %s 失败: %v", path, err)
+			} else {
+				logrus.Infof("%s 已解决并暂存。", path)
+			}
+
+			if quit {
+				logrus.Info("已退出，剩余冲突保持不变。")
+				return
+			}
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(gitResolveCmd)
+	gitResolveCmd.Flags().Bool("diff3", false, "先以 diff3 风格重新生成冲突标记以获得 base 内容（会覆盖文件中已做的修改）")
+	gitResolveCmd.Flags().Int("context", 15, "每处冲突发送给 AI 的上下文行数")
+	gitResolveCmd.Flags().StringP("prompt", "t", "", "自定义提示信息，可使用 {{.File}} {{.Ours}} {{.Base}} {{.Theirs}} {{.Before}} {{.After}} 等")
+	gitResolveCmd.Flags().Bool("allow-secrets", false, "检测到敏感信息时仍然发送（敏感内容会被屏蔽）")
+}
+
+func resolveTemplate(cmd *cobra.Command) (*template.Template, error) {
+	templateStr, err := cmd.Flags().GetString("prompt")
+	if err != nil {
+		return nil, fmt.Errorf("获取 prompt 标志失败: %v", err)
+	}
+
+	if templateStr == "" {
+		templateStr = os.Getenv("AICLI_RESOLVE_PROMPT")
+	}
+
+	if templateStr == "" {
+		templateStr = defaultResolvePrompt
+	}
+
+	tmpl, err := template.New("resolve").Parse(templateStr)
+	if err != nil {
+		return nil, fmt.Errorf("解析模板失败: %v", err)
+	}
+	return tmpl, nil
+}
+
+// resolveHunk 为一处冲突生成解决方案并由用户决定如何处理，返回 false 表示用户选择退出
+func resolveHunk(cmd *cobra.Command, tmpl *template.Template, path string, h *conflict.Hunk) bool {
+	fmt.Printf("ours (%s):\n%s", h.OursLabel, indent(strings.TrimSuffix(h.Ours, "\n"), "    ")+"\n")
+	fmt.Printf("theirs (%s):\n%s", h.TheirsLabel, indent(strings.TrimSuffix(h.Theirs, "\n"), "    ")+"\n")
+
+	proposal, err := proposeResolution(cmd, tmpl, path, h)
+	if err != nil {
+		logrus.Errorf("生成解决方案失败: %v", err)
+	} else {
+		fmt.Printf("\n建议的解决方案:\n%s\n", indent(strings.TrimSuffix(proposal.Resolution, "\n"), "    "))
+		fmt.Printf("说明: %s\n", proposal.Explanation)
+	}
+
+	for {
+		input := strings.ToLower(readLine("\n[a 接受 / e 编辑 / o 使用 ours / t 使用 theirs / s 跳过 / q 退出]: "))
+		switch input {
+		case "a":
+			if err := checkAcceptable(proposal); err != nil {
+				fmt.Println(err)
+				continue
+			}
+			h.Resolve(proposal.Resolution)
+			return true
+		case "e":
+			initial := h.Ours
+			if proposal != nil {
+				initial = proposal.Resolution
+			}
+			edited, err := editCode(initial, "conflict_*"+filepath.Ext(path))
+			if err != nil {
+				logrus.Error(err)
+				continue
+			}
+			h.Resolve(edited)
+			return true
+		case "o":
+			h.Resolve(h.Ours)
+			return true
+		case "t":
+			h.Resolve(h.Theirs)
+			return true
+		case "s":
+			return true
+		case "q":
+			return false
+		}
+	}
+}
+
+// checkAcceptable 检查方案能否直接接受。使用 --allow-secrets 时 AI 看到的是屏蔽后的内容，
+// 方案中可能带有屏蔽占位符，直接写回会破坏文件，只能编辑后使用。
+func checkAcceptable(proposal *resolution) error {
+	if proposal == nil {
+		return fmt.Errorf("没有可接受的方案")
+	}
+	if secrets.ContainsMask(proposal.Resolution) {
+		return fmt.Errorf("方案中含有屏蔽占位符，不能直接接受，请使用 e 编辑后还原原始内容")
+	}
+	return nil
+}
+
+func proposeResolution(cmd *cobra.Command, tmpl *template.Template, path string, h *conflict.Hunk) (*resolution, error) {
+	guarded, err := guardSecrets(cmd, h.Before, h.Ours, h.Base, h.Theirs, h.After)
+	if err != nil {
+		return nil, err
+	}
+
+	var promptBuffer bytes.Buffer
+	err = tmpl.Execute(&promptBuffer, struct {
+		File        string
+		OursLabel   string
+		TheirsLabel string
+		HasBase     bool
+		Before      string
+		Ours        string
+		Base        string
+		Theirs      string
+		After       string
+	}{
+		File:        path,
+		OursLabel:   h.OursLabel,
+		TheirsLabel: h.TheirsLabel,
+		HasBase:     h.HasBase,
+		Before:      guarded[0],
+		Ours:        guarded[1],
+		Base:        guarded[2],
+		Theirs:      guarded[3],
+		After:       guarded[4],
+	})
+	if err != nil {
+		return nil, fmt.Errorf("执行模板失败: %v", err)
+	}
+
+	response, err := provider.GenerateContent(promptBuffer.String())
+	if err != nil {
+		return nil, err
+	}
+
+	start := strings.Index(response, "{")
+	end := strings.LastIndex(response, "}")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("回复中没有找到 JSON: %s", response)
+	}
+	var r resolution
+	if err := json.Unmarshal([]byte(response[start:end+1]), &r); err != nil {
+		return nil, fmt.Errorf("解析解决方案失败: %v", err)
+	}
+	return &r, nil
+}
+
+// filterPaths 只保留用户指定的冲突文件，参数为相对当前目录的路径
+func filterPaths(repo *githelper.Repo, files, args []string) []string {
+	wanted := map[string]bool{}
+	for _, a := range args {
+		abs, err := filepath.Abs(a)
+		if err != nil {
+			continue
+		}
+		rel, err := filepath.Rel(repo.Dir, abs)
+		if err != nil {
+			continue
+		}
+		wanted[filepath.ToSlash(rel)] = true
+	}
+
+	var result []string
+	for _, f := range files {
+		if wanted[f] {
+			result = append(result, f)
+		}
+	}
+	return result
+}
@@ -0,0 +1,21 @@
+package cmd
+
+import "testing"
+
+func TestCheckAcceptable(t *testing.T) {
+	tests := []struct {
+		name     string
+		proposal *resolution
+		ok       bool
+	}{
+		{"no proposal", nil, false},
+		{"plain", &resolution{Resolution: "a := 1\n"}, true},
+		{"masked secret", &resolution{Resolution: "token := \"<已屏蔽:github-token>\"\n"}, false},
+		{"masked private key", &resolution{Resolution: "<已屏蔽:private-key>\n"}, false},
+	}
+	for _, tt := range tests {
+		if err := checkAcceptable(tt.proposal); (err == nil) != tt.ok {
+			t.Errorf("%s: checkAcceptable() = %v, want ok=%v", tt.name, err, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,282 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/fanook/aicli/internal/commitmsg"
+	"github.com/fanook/aicli/internal/githelper"
+	"github.com/fanook/aicli/internal/provider"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"os"
+	"strings"
+	"text/template"
+)
+
+const defaultSplitPrompt = "你是一个帮助整理 Git 提交的助手。工作区中积累了多处互不相关的变更，请根据以下差异，把变更的文件划分为若干组逻辑上独立、内聚的提交，并为每组生成 commit 信息。\n\n要求：\n- 每个文件必须且只能出现在一个组中\n- 按合理的提交顺序排列，被依赖的改动放在前面\n- commit 信息格式：{{.Format}}\n{{if .Language}}- commit 信息的描述使用{{.Language}}撰写\n{{end}}- 只输出 JSON 数组，不要输出其他内容，格式如下：\n[{\"message\": \"commit 信息\", \"files\": [\"路径1\", \"路径2\"]}]\n\n变更文件：\n{{.Files}}\n\n变更内容：\n{{.Changes}}"
+
+const bracketFormat = "\"[类别] 描述\"，类别只能是 feat（新功能）或 fix（修复）"
+
+const conventionalFormat = "Conventional Commits 格式 \"<类型>(<范围>): <描述>\"，类型只能是 feat、fix、refactor、docs、test、chore、perf、build、ci 之一"
+
+// commitGroup 是拆分计划中的一个提交
+type commitGroup struct {
+	Message string   `json:"message"`
+	Files   []string `json:"files"`
+}
+
+// runSplit 让 AI 将工作区的全部变更拆分为多个提交，经用户确认或调整后依次暂存并提交。
+// 拆分以文件为单位，提交前会清空暂存区，因此存在部分暂存的文件时拒绝执行，避免丢失按块暂存的结果。
+func runSplit(cmd *cobra.Command, repo *githelper.Repo, commitOpts githelper.CommitOptions) {
+	partial, err := repo.PartiallyStagedFiles()
+	if err != nil {
+		logrus.Fatalf("获取 Git 变更信息失败: %v", err)
+	}
+	if len(partial) > 0 {
+		logrus.Fatalf("以下文件只暂存了部分修改，--split 按文件拆分会丢失按块暂存的结果，请先提交或取消暂存后再拆分: %s", strings.Join(partial, ", "))
+	}
+
+	maxTokens, _ := cmd.Flags().GetInt("max-tokens")
+	maxFileLines, _ := cmd.Flags().GetInt("max-file-lines")
+	files, err := repo.Diff(githelper.DiffOptions{Base: "HEAD", MaxFileLines: maxFileLines, TokenBudget: maxTokens})
+	if err != nil {
+		logrus.Fatalf("获取 Git 差异失败: %v", err)
+	}
+
+	var paths []string
+	var fileList strings.Builder
+	for _, f := range files {
+		paths = append(paths, f.Path)
+		fileList.WriteString(fmt.Sprintf("%s\t%s\n", f.Status, f.Path))
+	}
+
+	unstaged, err := repo.UnstagedFiles()
+	if err != nil {
+		logrus.Fatalf("获取 Git 变更信息失败: %v", err)
+	}
+	for _, f := range unstaged {
+		if f.Status == "??" {
+			paths = append(paths, f.Path)
+			fileList.WriteString(fmt.Sprintf("??\t%s (新文件，未跟踪)\n", f.Path))
+		}
+	}
+
+	if len(paths) == 0 {
+		logrus.Info("当前没有任何变更，无需提交。")
+		return
+	}
+
+	guarded, err := guardSecrets(cmd, githelper.FormatDiff(files))
+	if err != nil {
+		logrus.Fatal(err)
+	}
+
+	plan, err := generateSplitPlan(cmd, repo, strings.TrimSpace(fileList.String()), guarded[0], paths)
+	if err != nil {
+		logrus.Fatalf("生成拆分计划失败: %v", err)
+	}
+
+	for {
+		fmt.Printf("\n拆分计划:\n\n%s\n", formatPlan(plan))
+		input := strings.ToLower(readLine("确认按此计划提交？[y 确认 / e 编辑 / q 取消]: "))
+		if input == "q" || input == "n" {
+			logrus.Info("操作已取消。")
+			return
+		}
+		if input == "y" {
+			break
+		}
+		if input == "e" {
+			edited, err := editText(planHelp+formatPlan(plan), "split_plan_*.txt")
+			if err != nil {
+				logrus.Fatal(err)
+			}
+			editedPlan, err := parsePlan(edited, paths)
+			if err != nil {
+				logrus.Errorf("计划格式有误，已保留修改前的计划: %v", err)
+				continue
+			}
+			plan = editedPlan
+		}
+	}
+
+	if err := repo.UnstageAll(); err != nil {
+		logrus.Fatalf("清空暂存区失败: %v", err)
+	}
+
+	for i, group := range plan {
+		if err := repo.StagePaths(group.Files); err != nil {
+			logrus.Fatalf("暂存第 %d 组文件失败: %v", i+1, err)
+		}
+		hash, err := repo.Commit(group.Message, commitOpts)
+		if err != nil {
+			logrus.Fatalf("提交第 %d 组失败: %v", i+1, err)
+		}
+		logrus.Infof("[%d/%d] %s %s", i+1, len(plan), hash[:7], strings.SplitN(group.Message, "\n", 2)[0])
+	}
+	logrus.Info("成功。")
+}
+
+// generateSplitPlan 调用 AI 生成拆分计划，并补全遗漏的文件
+func generateSplitPlan(cmd *cobra.Command, repo *githelper.Repo, fileList, changes string, paths []string) ([]commitGroup, error) {
+	conventional, _ := cmd.Flags().GetBool("conventional")
+	format, fallback := bracketFormat, "[fix] 其他改动"
+	if conventional {
+		format, fallback = conventionalFormat, "chore: 其他改动"
+	}
+
+	templateStr, _ := cmd.Flags().GetString("prompt")
+	if templateStr == "" {
+		templateStr = os.Getenv("AICLI_GITCOMMIT_SPLIT_PROMPT")
+	}
+	if templateStr == "" {
+		templateStr = defaultSplitPrompt
+	}
+
+	tmpl, err := template.New("split").Parse(templateStr)
+	if err != nil {
+		return nil, fmt.Errorf("解析模板失败: %v", err)
+	}
+
+	language := outputLanguage(cmd, repo)
+	var promptBuffer bytes.Buffer
+	err = tmpl.Execute(&promptBuffer, struct {
+		Format   string
+		Files    string
+		Changes  string
+		Language string
+	}{
+		Format:   format,
+		Files:    fileList,
+		Changes:  changes,
+		Language: language,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("执行模板失败: %v", err)
+	}
+
+	response, err := provider.GenerateContent(withLanguage(promptBuffer.String(), templateStr, language))
+	if err != nil {
+		return nil, err
+	}
+
+	start := strings.Index(response, "[")
+	end := strings.LastIndex(response, "]")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("回复中没有找到 JSON 数组: %s", response)
+	}
+	var plan []commitGroup
+	if err := json.Unmarshal([]byte(response[start:end+1]), &plan); err != nil {
+		return nil, fmt.Errorf("解析拆分计划失败: %v", err)
+	}
+
+	if conventional {
+		for i := range plan {
+			plan[i].Message = commitmsg.Normalize(plan[i].Message)
+			if err := commitmsg.ValidateConventional(plan[i].Message); err != nil {
+				logrus.Warnf("第 %d 组的 commit 信息不符合规范，请在确认前编辑: %v", i+1, err)
+			}
+		}
+	}
+
+	return normalizePlan(plan, paths, fallback), nil
+}
+
+// normalizePlan 去掉未知、重复的文件和空组，遗漏的文件归入最后一个使用 fallback 信息的组
+func normalizePlan(plan []commitGroup, paths []string, fallback string) []commitGroup {
+	remaining := map[string]bool{}
+	for _, p := range paths {
+		remaining[p] = true
+	}
+
+	var result []commitGroup
+	for _, group := range plan {
+		var files []string
+		for _, f := range group.Files {
+			if remaining[f] {
+				files = append(files, f)
+				delete(remaining, f)
+			}
+		}
+		if len(files) > 0 && strings.TrimSpace(group.Message) != "" {
+			result = append(result, commitGroup{Message: strings.TrimSpace(group.Message), Files: files})
+		}
+	}
+
+	var missed []string
+	for _, p := range paths {
+		if remaining[p] {
+			missed = append(missed, p)
+		}
+	}
+	if len(missed) > 0 {
+		result = append(result, commitGroup{Message: fallback, Files: missed})
+	}
+	return result
+}
+
+const planHelp = `# 每个提交以 "commit: <信息>" 开头，随后每行一个文件，按从上到下的顺序提交。
+# 多行 commit 信息的后续行以 "| " 开头。可以移动文件、修改信息或删除整个提交，
+# 未出现在任何提交中的文件将保留在工作区。以 # 开头的行会被忽略。
+
+`
+
+// formatPlan 将拆分计划格式化为可编辑的文本
+func formatPlan(plan []commitGroup) string {
+	var b strings.Builder
+	for _, group := range plan {
+		lines := strings.Split(group.Message, "\n")
+		b.WriteString("commit: " + lines[0] + "\n")
+		for _, l := range lines[1:] {
+			b.WriteString("| " + l + "\n")
+		}
+		for _, f := range group.Files {
+			b.WriteString("  " + f + "\n")
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// parsePlan 解析用户编辑后的拆分计划，只保留确实存在变更的文件
+func parsePlan(text string, paths []string) ([]commitGroup, error) {
+	known := map[string]bool{}
+	for _, p := range paths {
+		known[p] = true
+	}
+
+	var plan []commitGroup
+	for _, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "" || strings.HasPrefix(trimmed, "#"):
+		case strings.HasPrefix(trimmed, "commit:"):
+			plan = append(plan, commitGroup{Message: strings.TrimSpace(strings.TrimPrefix(trimmed, "commit:"))})
+		case strings.HasPrefix(line, "|"):
+			if len(plan) == 0 {
+				return nil, fmt.Errorf("信息续行 %q 之前没有 commit 行", line)
+			}
+			plan[len(plan)-1].Message += "\n" + strings.TrimPrefix(strings.TrimPrefix(line, "|"), " ")
+		default:
+			if len(plan) == 0 {
+				return nil, fmt.Errorf("文件 %s 之前没有 commit 行", trimmed)
+			}
+			if !known[trimmed] {
+				return nil, fmt.Errorf("文件 %s 没有变更", trimmed)
+			}
+			plan[len(plan)-1].Files = append(plan[len(plan)-1].Files, trimmed)
+		}
+	}
+
+	var result []commitGroup
+	for _, group := range plan {
+		if len(group.Files) > 0 && strings.TrimSpace(group.Message) != "" {
+			result = append(result, group)
+		}
+	}
+	if len(result) == 0 {
+		return nil, fmt.Errorf("计划中没有任何提交")
+	}
+	return result, nil
+}
@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// stdinReader 是所有交互式输入共用的读取器，避免多个缓冲区争抢标准输入
+var stdinReader = bufio.NewReader(os.Stdin)
+
+// readLine 打印提示并读取一行用户输入
+func readLine(prompt string) string {
+	fmt.Print(prompt)
+	line, _ := stdinReader.ReadString('\n')
+	return strings.TrimSpace(line)
+}
+
+// confirm 询问用户是否继续，仅输入 y 时返回 true
+func confirm(prompt string) bool {
+	return strings.ToLower(readLine(prompt+" [y/n]: ")) == "y"
+}
+
+// parseSelection 解析形如 "1 3 5-7" 或 "a" 的编号选择，返回从 0 开始的下标
+func parseSelection(input string, n int) ([]int, error) {
+	input = strings.TrimSpace(input)
+	if input == "a" || input == "all" {
+		all := make([]int, n)
+		for i := range all {
+			all[i] = i
+		}
+		return all, nil
+	}
+
+	seen := map[int]bool{}
+	var selected []int
+	for _, field := range strings.FieldsFunc(input, func(r rune) bool { return r == ' ' || r == ',' }) {
+		start, end := field, field
+		if i := strings.Index(field, "-"); i > 0 {
+			start, end = field[:i], field[i+1:]
+		}
+		from, err := strconv.Atoi(start)
+		if err != nil {
+			return nil, fmt.Errorf("无效的编号: %s", field)
+		}
+		to, err := strconv.Atoi(end)
+		if err != nil {
+			return nil, fmt.Errorf("无效的编号: %s", field)
+		}
+		if from < 1 || to > n || from > to {
+			return nil, fmt.Errorf("编号超出范围 (1-%d): %s", n, field)
+		}
+		for i := from; i <= to; i++ {
+			if !seen[i-1] {
+				seen[i-1] = true
+				selected = append(selected, i-1)
+			}
+		}
+	}
+	return selected, nil
+}
@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"github.com/fanook/aicli/internal/config"
+	"github.com/fanook/aicli/internal/githelper"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"os"
+	"strings"
+)
+
+// languageNames 将常用的语言代码转换为写入提示词的语言名称
+var languageNames = map[string]string{
+	"zh":    "简体中文",
+	"zh-cn": "简体中文",
+	"zh-tw": "繁體中文",
+	"en":    "English",
+	"ja":    "日本語",
+	"ko":    "한국어",
+	"de":    "Deutsch",
+	"fr":    "Français",
+	"es":    "Español",
+}
+
+// outputLanguage 确定生成内容使用的语言，优先级为 --lang 参数、仓库 .aicli.yaml 中的 lang、
+// AICLI_LANG 环境变量。repo 为空时尝试使用当前目录所在的仓库。都未设置时返回空字符串，由提示模板决定默认语言。
+func outputLanguage(cmd *cobra.Command, repo *githelper.Repo) string {
+	lang, _ := cmd.Flags().GetString("lang")
+	if lang == "" && repo == nil {
+		repo, _ = githelper.Open("")
+	}
+	if lang == "" && repo != nil {
+		cfg, err := config.LoadRepo(repo.Dir)
+		if err != nil {
+			logrus.Warnf("读取仓库配置失败: %v", err)
+		}
+		lang = cfg.Lang
+	}
+	if lang == "" {
+		lang = os.Getenv("AICLI_LANG")
+	}
+	return languageName(lang)
+}
+
+// withLanguage 在模板没有使用 {{.Language}} 时把语言要求追加到提示末尾，
+// 使自定义提示同样遵循 --lang、.aicli.yaml 和 AICLI_LANG 的设置
+func withLanguage(prompt, templateStr, language string) string {
+	if language == "" || strings.Contains(templateStr, ".Language") {
+		return prompt
+	}
+	return prompt + "\n\n语言要求：生成的内容请使用" + language + "撰写。"
+}
+
+// languageName 返回语言代码对应的名称，未知的代码原样返回，便于直接写 "Deutsch" 等名称
+func languageName(lang string) string {
+	lang = strings.TrimSpace(lang)
+	if name, ok := languageNames[strings.ToLower(strings.Replace(lang, "_", "-", -1))]; ok {
+		return name
+	}
+	return lang
+}
@@ -0,0 +1,184 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/fanook/aicli/internal/githelper"
+	"github.com/fanook/aicli/internal/provider"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+const defaultPRDescPrompt = "你是一个帮助编写 Pull Request 的助手。请根据以下分支的提交记录和代码差异，生成 Pull Request 的标题和描述。\n\n输出要求（严格遵循）：\n- 第一行以 \"# \" 开头，为不超过 72 个字符的标题\n- 之后为 Markdown 格式的描述{{if .PRTemplate}}，按照下方仓库提供的 PR 模板的结构填写，保留模板中的标题{{else}}，包含以下三个小节：\n  ## 概述：一到两句话说明这个 PR 做了什么、为什么\n  ## 变更内容：按要点列出主要改动\n  ## 测试说明：说明如何验证这些改动{{end}}\n{{if .Language}}- 标题和描述使用{{.Language}}撰写{{if .PRTemplate}}，模板中的标题保持原样{{end}}\n{{end}}- 不要输出其他多余内容\n\n分支：{{.Branch}} -> {{.Base}}\n\n提交记录：\n{{.Commits}}\n{{if .PRTemplate}}\nPR 模板：\n{{.PRTemplate}}\n{{end}}\n代码差异：\n{{.Changes}}"
+
+// prTemplatePaths 是 GitHub 支持的 PR 模板位置
+var prTemplatePaths = []string{
+	".github/pull_request_template.md",
+	".github/PULL_REQUEST_TEMPLATE.md",
+	"PULL_REQUEST_TEMPLATE.md",
+	"pull_request_template.md",
+	"docs/pull_request_template.md",
+	"docs/PULL_REQUEST_TEMPLATE.md",
+}
+
+var prDescCmd = &cobra.Command{
+	Use:   "pr-desc",
+	Short: "根据分支变更生成 Pull Request 标题和描述",
+	Long: `将当前分支与主分支进行比较，结合提交记录和代码差异，使用 AI 生成 Pull Request 的标题和 Markdown 描述。
+仓库中存在 PR 模板（如 .github/pull_request_template.md）时会按照模板填写。`,
+	Example: `  acl pr-desc
+  acl pr-desc --base develop -o pr.md`,
+	Run: func(cmd *cobra.Command, args []string) {
+		repo, err := githelper.Open("")
+		if err != nil {
+			logrus.Fatal(err)
+		}
+
+		base, _ := cmd.Flags().GetString("base")
+		if base == "" {
+			base, err = repo.DefaultBranch()
+			if err != nil {
+				logrus.Fatalf("%v，请使用 --base 指定", err)
+			}
+		}
+
+		branch, err := repo.CurrentBranch()
+		if err != nil {
+			logrus.Fatalf("获取当前分支失败: %v", err)
+		}
+
+		commits, err := repo.Log(githelper.LogOptions{Range: base + "..HEAD", NoMerges: true})
+		if err != nil {
+			logrus.Fatalf("获取提交记录失败: %v", err)
+		}
+		if len(commits) == 0 {
+			logrus.Infof("当前分支相对 %s 没有新的提交。", base)
+			return
+		}
+
+		maxTokens, _ := cmd.Flags().GetInt("max-tokens")
+		maxFileLines, _ := cmd.Flags().GetInt("max-file-lines")
+		files, err := repo.Diff(githelper.DiffOptions{Base: base + "...HEAD", MaxFileLines: maxFileLines, TokenBudget: maxTokens})
+		if err != nil {
+			logrus.Fatalf("获取 Git 差异失败: %v", err)
+		}
+
+		var commitLog strings.Builder
+		for _, c := range commits {
+			commitLog.WriteString(fmt.Sprintf("- %s %s\n", c.ShortHash(), c.Subject))
+			if c.Body != "" {
+				commitLog.WriteString(indent(c.Body, "  ") + "\n")
+			}
+		}
+
+		guarded, err := guardSecrets(cmd, strings.TrimSpace(commitLog.String()), githelper.FormatDiff(files))
+		if err != nil {
+			logrus.Fatal(err)
+		}
+
+		prTemplate := ""
+		noTemplate, _ := cmd.Flags().GetBool("no-template")
+		if !noTemplate {
+			prTemplate = readPRTemplate(repo.Dir)
+		}
+
+		templateStr, err := cmd.Flags().GetString("prompt")
+		if err != nil {
+			logrus.Fatalf("获取 prompt 标志失败: %v", err)
+		}
+
+		if templateStr == "" {
+			templateStr = os.Getenv("AICLI_PRDESC_PROMPT")
+		}
+
+		if templateStr == "" {
+			templateStr = defaultPRDescPrompt
+		}
+
+		tmpl, err := template.New("prdesc").Parse(templateStr)
+		if err != nil {
+			logrus.Fatalf("解析模板失败: %v", err)
+		}
+
+		language := outputLanguage(cmd, repo)
+		var promptBuffer bytes.Buffer
+		err = tmpl.Execute(&promptBuffer, struct {
+			Branch     string
+			Base       string
+			Commits    string
+			Changes    string
+			PRTemplate string
+			Language   string
+		}{
+			Branch:     branch,
+			Base:       base,
+			Commits:    guarded[0],
+			Changes:    guarded[1],
+			PRTemplate: prTemplate,
+			Language:   language,
+		})
+		if err != nil {
+			logrus.Fatalf("执行模板失败: %v", err)
+		}
+
+		response, err := provider.GenerateContent(withLanguage(promptBuffer.String(), templateStr, language))
+		if err != nil {
+			logrus.Fatalf("生成 PR 描述失败: %v", err)
+		}
+
+		title, body := splitTitle(response)
+
+		output, _ := cmd.Flags().GetString("output")
+		if output != "" {
+			content := fmt.Sprintf("# %s\n\n%s\n", title, body)
+			if err := ioutil.WriteFile(output, []byte(content), 0644); err != nil {
+				logrus.Fatalf("写入文件失败: %v", err)
+			}
+			logrus.Infof("PR 描述已写入 %s", output)
+			return
+		}
+
+		fmt.Printf("\n标题:\n%s\n\n描述:\n%s\n\n", title, body)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(prDescCmd)
+	prDescCmd.Flags().StringP("base", "b", "", "比较的目标分支，默认为 origin/main 或 origin/master，没有远程分支时使用本地的 main 或 master")
+	prDescCmd.Flags().StringP("output", "o", "", "将结果写入指定文件，而不是打印到终端")
+	prDescCmd.Flags().Bool("no-template", false, "忽略仓库中的 PR 模板")
+	prDescCmd.Flags().StringP("prompt", "t", "", "自定义提示信息，可使用 {{.Branch}} {{.Base}} {{.Commits}} {{.Changes}} {{.PRTemplate}} {{.Language}}")
+	prDescCmd.Flags().String("lang", "", "生成内容使用的语言，如 zh、en，默认读取仓库 .aicli.yaml 中的 lang 或 AICLI_LANG")
+	prDescCmd.Flags().Int("max-tokens", 8000, "发送给 AI 的差异内容的 token 预算，超出部分仅保留文件摘要")
+	prDescCmd.Flags().Int("max-file-lines", 200, "单个文件保留的最大差异行数")
+	prDescCmd.Flags().Bool("allow-secrets", false, "检测到敏感信息时仍然发送（敏感内容会被屏蔽）")
+}
+
+// readPRTemplate 读取仓库中的 PR 模板，不存在时返回空字符串
+func readPRTemplate(dir string) string {
+	for _, p := range prTemplatePaths {
+		data, err := ioutil.ReadFile(filepath.Join(dir, p))
+		if err == nil {
+			return strings.TrimSpace(string(data))
+		}
+	}
+	return ""
+}
+
+// splitTitle 将 AI 回复拆分为标题和正文，标题取第一行并去掉 Markdown 标记
+func splitTitle(response string) (string, string) {
+	response = strings.TrimSpace(response)
+	parts := strings.SplitN(response, "\n", 2)
+	title := strings.TrimSpace(strings.TrimLeft(parts[0], "# "))
+	title = strings.TrimPrefix(strings.TrimPrefix(title, "标题："), "标题:")
+	body := ""
+	if len(parts) == 2 {
+		body = strings.TrimSpace(parts[1])
+	}
+	return strings.TrimSpace(title), body
+}
@@ -6,9 +6,15 @@ import (
 	"database/sql"
 	"encoding/csv"
 	"fmt"
+	"github.com/fanook/aicli/internal/githelper"
 	"github.com/fanook/aicli/internal/provider"
+	"github.com/fanook/aicli/internal/ratelimit"
 	"os"
+	"os/signal"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
 	"text/template"
 	"time"
 
@@ -25,7 +31,13 @@ var processDataCmd = &cobra.Command{
 	Long: `该命令从指定的数据源（数据库或CSV文件）中读取数据行，
 要求每行必须包含 id、content、prompt、result 四个字段，其中 result 为空表示未处理。
 根据参数传递的prompt或行prompt,填充content后，生成最终 prompt。
-再调用 AI 接口生成回复，最后将生成结果写入result字段。`,
+再调用 AI 接口生成回复，最后将生成结果写入result字段。
+
+使用 --concurrency 可以同时处理多行，CSV 输出仍保持输入的行顺序。
+--rpm 和 --tpm 限制每分钟的请求数和 token 数，未指定时读取当前提供商的
+AICLI_<PROVIDER>_RPM 和 AICLI_<PROVIDER>_TPM 环境变量。
+处理过程中按下 Ctrl-C 会停止派发新的行，等待进行中的行完成并保存后退出，再次按下则立即退出。
+CSV 中 result 已有内容的行会被跳过，可以将上次的输出作为输入继续处理。`,
 	Run: func(cmd *cobra.Command, args []string) {
 		runProcessData()
 	},
@@ -48,6 +60,11 @@ var (
 
 	// 全局 AI处理提示语（模板），如果为空则每行使用自身的 prompt 字段
 	promptText string
+
+	// 同时处理的行数，以及每分钟请求数和 token 数的限制（0 表示使用环境变量中的配置）
+	concurrency int
+	rpmLimit    int
+	tpmLimit    int
 )
 
 func init() {
@@ -66,122 +83,207 @@ func init() {
 
 	// 全局提示参数
 	processDataCmd.Flags().StringVarP(&promptText, "prompt", "p", "", "全局AI处理提示语模板，若为空则使用每行数据中的 prompt 字段")
+
+	// 并发与限流参数
+	processDataCmd.Flags().IntVarP(&concurrency, "concurrency", "c", 1, "同时处理的行数")
+	processDataCmd.Flags().IntVar(&rpmLimit, "rpm", 0, "每分钟最多发起的请求数，默认读取 AICLI_<PROVIDER>_RPM，0 表示不限制")
+	processDataCmd.Flags().IntVar(&tpmLimit, "tpm", 0, "每分钟最多消耗的 token 数（估算值），默认读取 AICLI_<PROVIDER>_TPM，0 表示不限制")
 }
 
 func runProcessData() {
+	if concurrency < 1 {
+		logrus.Fatal("--concurrency 必须大于 0")
+	}
+	limiter, err := ratelimit.ForProvider(provider.Name(), rpmLimit, tpmLimit)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+
+	p := &dataProcessor{limiter: limiter}
+	if promptText != "" {
+		p.tmpl, err = template.New("prompt").Parse(promptText)
+		if err != nil {
+			logrus.Fatalf("解析全局提示模板失败: %v", err)
+		}
+	}
+
+	ctx, stop := drainOnInterrupt()
+	defer stop()
+
 	switch strings.ToLower(sourceType) {
 	case "csv":
 		if csvFile == "" {
 			logrus.Fatal("当数据源为csv时，必须指定--csv-file参数")
 		}
-		processCSV(promptText, csvFile, csvOut)
+		processCSV(ctx, p, csvFile, csvOut)
 	case "db":
 		if dbName == "" || dbTable == "" {
 			logrus.Fatal("当数据源为db时，必须指定--db-name 和 --db-table 参数")
 		}
-		processDB(promptText, dbHost, dbPort, dbUser, dbPass, dbName, dbTable)
+		processDB(ctx, p, dbHost, dbPort, dbUser, dbPass, dbName, dbTable)
 	default:
 		logrus.Fatalf("未知的数据源类型：%s", sourceType)
 	}
+
+	// 中断时已处理的行都已保存，以非零退出码告知调用方处理未完成
+	if ctx.Err() != nil {
+		stop()
+		os.Exit(130)
+	}
 }
 
-// processCSV 从CSV文件中读取数据，调用AI生成回复后输出到新的CSV文件
-// CSV 文件中每行必须有四列：id, content, prompt, result
-func processCSV(globalPrompt, inputFile, outputFile string) {
-	file, err := os.Open(inputFile)
-	if err != nil {
-		logrus.Fatalf("打开CSV文件失败: %v", err)
+// dataProcessor 为每行数据生成提示并调用 AI，可以被多个 worker 同时使用
+type dataProcessor struct {
+	// tmpl 为全局提示模板，为 nil 时使用每行自身的 prompt 字段
+	tmpl    *template.Template
+	limiter *ratelimit.Limiter
+	total   int
+
+	started int64
+	done    int64
+	failed  int64
+}
+
+// reply 为一行数据生成回复，ok 为 false 表示该行未处理成功，结果不应写回。
+// ctx 取消后不再发起新的请求，已经发出的请求会等待完成。
+func (p *dataProcessor) reply(ctx context.Context, id, rowPrompt, content string) (reply string, ok bool) {
+	if ctx.Err() != nil {
+		return "", false
 	}
-	defer file.Close()
 
-	reader := csv.NewReader(file)
-	records, err := reader.ReadAll()
+	prompt, err := p.render(rowPrompt, content)
 	if err != nil {
-		logrus.Fatalf("读取CSV文件失败: %v", err)
+		logrus.Errorf("ID %s %v", id, err)
+		atomic.AddInt64(&p.failed, 1)
+		return "", false
 	}
-
-	if len(records) == 0 {
-		logrus.Info("CSV文件为空")
-		return
+	if err := p.limiter.Wait(ctx, githelper.EstimateTokens(prompt)); err != nil {
+		return "", false
 	}
 
-	header := records[0]
-	if len(header) < 4 {
-		logrus.Fatal("CSV表头列数不足，要求至少有 id, content, prompt, result 四列")
+	n := atomic.AddInt64(&p.started, 1)
+	logrus.Infof("正在处理 [%d/%d]，ID: %s", n, p.total, id)
+	reply, err = generateWithTimeout(prompt, 60*time.Second)
+	if err != nil {
+		logrus.Errorf("ID %s 生成回复失败: %v", id, err)
+		atomic.AddInt64(&p.failed, 1)
+		return "", false
 	}
-	outputRecords := [][]string{header}
+	p.limiter.Add(githelper.EstimateTokens(reply))
+	atomic.AddInt64(&p.done, 1)
+	return reply, true
+}
 
-	var tmpl *template.Template
-	if globalPrompt != "" {
-		tmpl, err = template.New("prompt").Parse(globalPrompt)
+// render 使用全局模板或行模板填充 content，得到最终的提示
+func (p *dataProcessor) render(rowPrompt, content string) (string, error) {
+	tmpl := p.tmpl
+	if tmpl == nil {
+		var err error
+		tmpl, err = template.New("prompt").Parse(rowPrompt)
 		if err != nil {
-			logrus.Fatalf("解析全局提示模板失败: %v", err)
+			return "", fmt.Errorf("解析提示模板失败: %v", err)
 		}
 	}
 
-	total := len(records) - 1
+	var buf bytes.Buffer
+	err := tmpl.Execute(&buf, struct {
+		Content string
+	}{
+		Content: content,
+	})
+	if err != nil {
+		return "", fmt.Errorf("填充提示模板失败: %v", err)
+	}
+	return buf.String(), nil
+}
 
-	for i, row := range records[1:] {
-		if len(row) < 4 {
-			logrus.Errorf("行 %d 列数不足，跳过", i+2)
-			outputRecords = append(outputRecords, row)
-			continue
-		}
+// summary 输出处理结果，中断时给出未处理的行数
+func (p *dataProcessor) summary(ctx context.Context, output string) {
+	done := atomic.LoadInt64(&p.done)
+	failed := atomic.LoadInt64(&p.failed)
+	if ctx.Err() != nil {
+		logrus.Warnf("处理已中断：成功 %d 行，失败 %d 行，剩余 %d 行未处理", done, failed, int64(p.total)-done-failed)
+	} else {
+		logrus.Infof("处理完成：成功 %d 行，失败 %d 行", done, failed)
+	}
+	if output != "" {
+		logrus.Infof("输出文件: %s", output)
+	}
+}
+
+// generateWithTimeout 调用 AI 生成回复，超过 timeout 时取消 HTTP 请求，避免超时的请求继续占用连接和配额
+func generateWithTimeout(prompt string, timeout time.Duration) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
 
-		id := row[0]
-		content := row[1]
-		existingPrompt := row[2]
+	reply, err := provider.GenerateContentContext(ctx, prompt)
+	if err != nil && ctx.Err() == context.DeadlineExceeded {
+		return "", fmt.Errorf("处理超时")
+	}
+	return reply, err
+}
 
-		logrus.Infof("正在处理 [%d/%d]，ID: %s", i+1, total, id)
+// drainOnInterrupt 返回第一次收到 Ctrl-C 或 SIGTERM 时取消的 context，用于停止派发新的行；
+// 再次收到信号时立即退出
+func drainOnInterrupt() (context.Context, func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 1)
+	stopped := make(chan struct{})
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
 
-		var finalPrompt string
-		var buf bytes.Buffer
-		if tmpl == nil {
-			tmpl, err = template.New("prompt").Parse(existingPrompt)
-			if err != nil {
-				logrus.Fatalf("解析行[id:%d]提示模板失败: %v", err, id)
-			}
+	go func() {
+		select {
+		case <-signals:
+		case <-stopped:
+			return
 		}
-		err = tmpl.Execute(&buf, struct {
-			Content string
-		}{
-			Content: content,
-		})
-		if err != nil {
-			logrus.Fatalf("填充行[id:%d]提示模板失败: %v", err, id)
-			finalPrompt = existingPrompt
-		} else {
-			finalPrompt = buf.String()
+		logrus.Warn("收到中断信号，等待进行中的行处理完成后退出，再次按下 Ctrl-C 立即退出")
+		cancel()
+
+		select {
+		case <-signals:
+			os.Exit(130)
+		case <-stopped:
 		}
+	}()
 
-		rowCtx, rowCancel := context.WithTimeout(context.Background(), 60*time.Second)
-		replyChan := make(chan string, 1)
-		errChan := make(chan error, 1)
+	return ctx, func() {
+		signal.Stop(signals)
+		close(stopped)
+		cancel()
+	}
+}
 
-		go func(prompt string) {
-			reply, err := provider.GenerateContent(prompt)
-			if err != nil {
-				errChan <- err
-			} else {
-				replyChan <- reply
-			}
-		}(finalPrompt)
+// processCSV 从CSV文件中读取数据，调用AI生成回复后输出到新的CSV文件
+// CSV 文件中每行必须有四列：id, content, prompt, result，result 已有内容的行保持不变。
+// 多个 worker 并发处理，结果按输入顺序写出，中断时未处理的行原样写出。
+func processCSV(ctx context.Context, p *dataProcessor, inputFile, outputFile string) {
+	file, err := os.Open(inputFile)
+	if err != nil {
+		logrus.Fatalf("打开CSV文件失败: %v", err)
+	}
+	defer file.Close()
 
-		var reply string
-		select {
-		case <-rowCtx.Done():
-			logrus.Errorf("行 %d (ID:%s) 处理超时", i+2, id)
-			reply = ""
-		case err := <-errChan:
-			logrus.Errorf("行 %d (ID:%s) 生成回复失败: %v", i+2, id, err)
-			reply = ""
-		case reply = <-replyChan:
+	reader := csv.NewReader(file)
+	records, err := reader.ReadAll()
+	if err != nil {
+		logrus.Fatalf("读取CSV文件失败: %v", err)
+	}
 
-		}
-		rowCancel()
+	if len(records) == 0 {
+		logrus.Info("CSV文件为空")
+		return
+	}
 
-		row[3] = reply
-		outputRecords = append(outputRecords, row)
+	header := records[0]
+	if len(header) < 4 {
+		logrus.Fatal("CSV表头列数不足，要求至少有 id, content, prompt, result 四列")
+	}
+	rows := records[1:]
+	for _, row := range rows {
+		if len(row) >= 4 && row[3] == "" {
+			p.total++
+		}
 	}
 
 	outFile, err := os.Create(outputFile)
@@ -191,17 +293,74 @@ func processCSV(globalPrompt, inputFile, outputFile string) {
 	defer outFile.Close()
 
 	writer := csv.NewWriter(outFile)
-	err = writer.WriteAll(outputRecords)
-	if err != nil {
+	if err := writer.Write(header); err != nil {
 		logrus.Fatalf("写入CSV文件失败: %v", err)
 	}
-	writer.Flush()
-	logrus.Infof("处理完成，输出文件: %s", outputFile)
+
+	type result struct {
+		index int
+		row   []string
+	}
+	jobs := make(chan int)
+	results := make(chan result)
+
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				row := rows[i]
+				if len(row) < 4 {
+					logrus.Errorf("行 %d 列数不足，跳过", i+2)
+				} else if row[3] == "" {
+					if reply, ok := p.reply(ctx, row[0], row[2], row[1]); ok {
+						row[3] = reply
+					}
+				}
+				results <- result{index: i, row: row}
+			}
+		}()
+	}
+	go func() {
+		for i := range rows {
+			jobs <- i
+		}
+		close(jobs)
+		wg.Wait()
+		close(results)
+	}()
+
+	// 先完成的行暂存，等前面的行都写出后再按顺序写出
+	pending := make(map[int][]string)
+	next := 0
+	for r := range results {
+		pending[r.index] = r.row
+		for row, ok := pending[next]; ok; row, ok = pending[next] {
+			if err := writer.Write(row); err != nil {
+				logrus.Fatalf("写入CSV文件失败: %v", err)
+			}
+			delete(pending, next)
+			next++
+		}
+		writer.Flush()
+	}
+	if err := writer.Error(); err != nil {
+		logrus.Fatalf("写入CSV文件失败: %v", err)
+	}
+	p.summary(ctx, outputFile)
+}
+
+// dbRow 是数据库中一条待处理的记录
+type dbRow struct {
+	id      int
+	content string
+	prompt  string
 }
 
 // processDB 从数据库中读取未处理的数据行，调用AI生成回复后更新记录
 // 数据库表必须有 id, content, prompt, result 四个字段，其中 result 为空表示未处理
-func processDB(globalPrompt, host, port, user, pass, dbName, tableName string) {
+func processDB(ctx context.Context, p *dataProcessor, host, port, user, pass, dbName, tableName string) {
 	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=Local",
 		user, pass, host, port, dbName)
 	db, err := sql.Open("mysql", dsn)
@@ -210,19 +369,9 @@ func processDB(globalPrompt, host, port, user, pass, dbName, tableName string) {
 	}
 	defer db.Close()
 
-	// 如果提供了全局提示模板，则先解析
-	var tmpl *template.Template
-	if globalPrompt != "" {
-		tmpl, err = template.New("prompt").Parse(globalPrompt)
-		if err != nil {
-			logrus.Fatalf("解析全局提示模板失败: %v", err)
-		}
-	}
-
 	// 先查询待处理记录的总数
-	var total int
 	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE (result IS NULL OR result = '')", tableName)
-	if err := db.QueryRowContext(context.Background(), countQuery).Scan(&total); err != nil {
+	if err := db.QueryRowContext(context.Background(), countQuery).Scan(&p.total); err != nil {
 		logrus.Fatalf("统计待处理记录失败: %v", err)
 	}
 
@@ -233,100 +382,64 @@ func processDB(globalPrompt, host, port, user, pass, dbName, tableName string) {
 	}
 	defer updateStmt.Close()
 
-	// 分页查询，每页处理指定数量的记录（这里示例为2）
-	pageSize := 2
-	totalProcessed := 0
-	startID := 0
-
-	for {
-		query := fmt.Sprintf("SELECT id, content, prompt, result FROM %s WHERE (result IS NULL OR result = '') AND id > %d ORDER BY id ASC LIMIT %d", tableName, startID, pageSize)
-		rows, err := db.QueryContext(context.Background(), query)
-		if err != nil {
-			logrus.Fatalf("分页查询失败: %v", err)
-		}
-
-		recordsInPage := 0
-		for rows.Next() {
-			var id int
-			var content, rowPrompt, result string
-			if err := rows.Scan(&id, &content, &rowPrompt, &result); err != nil {
-				logrus.Errorf("扫描数据失败: %v", err)
-				continue
-			}
-			recordsInPage++
-			totalProcessed++
-			// 此处显示当前处理进度和总数
-			logrus.Infof("正在处理 [%d/%d]，ID: %d", totalProcessed, total, id)
-
-			startID = id
-
-			// 根据是否存在全局提示，决定使用全局模板还是每行模板
-			var finalPrompt string
-			if globalPrompt != "" {
-				var buf bytes.Buffer
-				err = tmpl.Execute(&buf, struct {
-					Content string
-				}{
-					Content: content,
-				})
-				if err != nil {
-					logrus.Errorf("ID %d 模板执行失败: %v", id, err)
-					finalPrompt = rowPrompt
-				} else {
-					finalPrompt = buf.String()
+	jobs := make(chan dbRow)
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for r := range jobs {
+				id := fmt.Sprint(r.id)
+				reply, ok := p.reply(ctx, id, r.prompt, r.content)
+				if !ok {
+					continue
 				}
-			} else {
-				tmpl, err = template.New("prompt").Parse(rowPrompt)
-				if err != nil {
-					logrus.Fatalf("解析行[id:%d]提示模板失败: %v", err, id)
-				}
-				var buf bytes.Buffer
-				err = tmpl.Execute(&buf, struct {
-					Content string
-				}{
-					Content: content,
-				})
-				if err != nil {
-					logrus.Fatalf("填充行[id:%d]提示模板失败: %v", err, id)
-				} else {
-					finalPrompt = buf.String()
+				// 中断后仍需保存进行中的行，这里不使用 ctx
+				if _, err := updateStmt.ExecContext(context.Background(), reply, r.id); err != nil {
+					logrus.Errorf("ID %s 更新结果失败: %v", id, err)
 				}
 			}
+		}()
+	}
 
-			// 为每条记录设置超时
-			rowCtx, rowCancel := context.WithTimeout(context.Background(), 60*time.Second)
-			replyChan := make(chan string, 1)
-			errChan := make(chan error, 1)
-			go func(prompt string) {
-				reply, err := provider.GenerateContent(prompt)
-				if err != nil {
-					errChan <- err
-				} else {
-					replyChan <- reply
-				}
-			}(finalPrompt)
-
-			var reply string
-			select {
-			case <-rowCtx.Done():
-				logrus.Errorf("ID %d 处理超时", id)
-				reply = ""
-			case err := <-errChan:
-				logrus.Errorf("ID %d 生成回复失败: %v", id, err)
-				reply = ""
-			case reply = <-replyChan:
-				_, err = updateStmt.ExecContext(context.Background(), reply, id)
-				if err != nil {
-					logrus.Errorf("ID %d 更新结果失败: %v", id, err)
-				}
-			}
-			rowCancel()
+	// 分页查询，每页的记录数为并发数的两倍，保证 worker 不会空闲
+	pageSize := max(2, 2*concurrency)
+	startID := 0
+	for ctx.Err() == nil {
+		page, err := queryPendingRows(db, tableName, startID, pageSize)
+		if err != nil {
+			logrus.Fatalf("分页查询失败: %v", err)
 		}
-		rows.Close()
-
-		if recordsInPage == 0 {
-			logrus.Info("处理完成")
+		if len(page) == 0 {
 			break
 		}
+		for _, r := range page {
+			jobs <- r
+		}
+		startID = page[len(page)-1].id
+	}
+	close(jobs)
+	wg.Wait()
+	p.summary(ctx, "")
+}
+
+// queryPendingRows 查询 id 大于 startID 的一页未处理记录
+func queryPendingRows(db *sql.DB, tableName string, startID, pageSize int) ([]dbRow, error) {
+	query := fmt.Sprintf("SELECT id, content, prompt FROM %s WHERE (result IS NULL OR result = '') AND id > %d ORDER BY id ASC LIMIT %d", tableName, startID, pageSize)
+	rows, err := db.QueryContext(context.Background(), query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var page []dbRow
+	for rows.Next() {
+		var r dbRow
+		if err := rows.Scan(&r.id, &r.content, &r.prompt); err != nil {
+			logrus.Errorf("扫描数据失败: %v", err)
+			continue
+		}
+		page = append(page, r)
 	}
+	return page, rows.Err()
 }
@@ -0,0 +1,176 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/fanook/aicli/internal/githelper"
+	"github.com/fanook/aicli/internal/provider"
+	"github.com/fanook/aicli/internal/review"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"io/ioutil"
+	"os"
+	"strings"
+	"text/template"
+)
+
+const defaultReviewPrompt = "你是一名经验丰富的代码审查者。请审查以下文件的代码差异，找出缺陷、安全问题、性能问题以及可维护性问题，忽略纯格式和个人风格问题。\n\n差异中新增行和上下文行前已标注新文件的行号。\n\n请只输出一个 JSON 数组，不要输出其他内容。每个元素包含：\n- line: 问题所在的新文件行号\n- severity: 严重程度，只能是 info、minor、major、critical 之一\n- message: 问题描述\n- suggestion: 修改建议\n没有问题时输出 []。\n\n文件：{{.File}}\n\n差异：\n{{.Diff}}"
+
+var reviewCmd = &cobra.Command{
+	Use:   "review",
+	Short: "使用 AI 审查暂存区或分支的代码变更",
+	Long: `逐个文件将暂存区（或当前分支相对目标分支）的差异发送给 AI 进行代码审查，
+输出带有文件、行号、严重程度和修改建议的审查意见，支持 text、json、sarif 格式。
+存在不低于 --fail-on 指定严重程度的问题时以非零状态码退出，可用于本地 pre-push hook。`,
+	Example: `  acl review
+  acl review --base main --format sarif -o review.sarif
+  acl review --fail-on major`,
+	Run: func(cmd *cobra.Command, args []string) {
+		format, _ := cmd.Flags().GetString("format")
+		if format != "text" && format != "json" && format != "sarif" {
+			logrus.Fatalf("未知的输出格式: %s，可选值为 text、json、sarif", format)
+		}
+
+		failOn, _ := cmd.Flags().GetString("fail-on")
+		threshold, gate, err := review.ParseSeverity(failOn)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+
+		repo, err := githelper.Open("")
+		if err != nil {
+			logrus.Fatal(err)
+		}
+
+		maxFileLines, _ := cmd.Flags().GetInt("max-file-lines")
+		opts := githelper.DiffOptions{Staged: true, MaxFileLines: maxFileLines}
+		if base, _ := cmd.Flags().GetString("base"); base != "" {
+			opts = githelper.DiffOptions{Base: base + "...HEAD", MaxFileLines: maxFileLines}
+		}
+
+		files, err := repo.Diff(opts)
+		if err != nil {
+			logrus.Fatalf("获取 Git 差异失败: %v", err)
+		}
+
+		var reviewable []githelper.FileDiff
+		var patches []string
+		for _, f := range files {
+			if f.Excluded != "" || f.Status == "D" {
+				continue
+			}
+			reviewable = append(reviewable, f)
+			patches = append(patches, f.Patch)
+		}
+		if len(reviewable) == 0 {
+			logrus.Info("没有需要审查的变更。")
+			return
+		}
+
+		patches, err = guardSecrets(cmd, patches...)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+
+		templateStr, err := cmd.Flags().GetString("prompt")
+		if err != nil {
+			logrus.Fatalf("获取 prompt 标志失败: %v", err)
+		}
+
+		if templateStr == "" {
+			templateStr = os.Getenv("AICLI_REVIEW_PROMPT")
+		}
+
+		if templateStr == "" {
+			templateStr = defaultReviewPrompt
+		}
+
+		tmpl, err := template.New("review").Parse(templateStr)
+		if err != nil {
+			logrus.Fatalf("解析模板失败: %v", err)
+		}
+
+		var findings []review.Finding
+		var unparsed []string
+		for i, f := range reviewable {
+			logrus.Infof("正在审查 [%d/%d] %s", i+1, len(reviewable), f.Path)
+
+			var promptBuffer bytes.Buffer
+			err = tmpl.Execute(&promptBuffer, struct {
+				File string
+				Diff string
+			}{
+				File: f.Path,
+				Diff: review.AnnotatePatch(patches[i]),
+			})
+			if err != nil {
+				logrus.Fatalf("执行模板失败: %v", err)
+			}
+
+			// 回复无法解析时重试一次，仍然失败则记录下来，不能当作没有问题
+			var fileFindings []review.Finding
+			var parseErr error
+			for attempt := 0; attempt < 2; attempt++ {
+				response, err := provider.GenerateContent(promptBuffer.String())
+				if err != nil {
+					logrus.Fatalf("审查 %s 失败: %v", f.Path, err)
+				}
+				fileFindings, parseErr = review.ParseFindings(response, f.Path)
+				if parseErr == nil {
+					break
+				}
+				logrus.Warnf("%s: %v", f.Path, parseErr)
+			}
+			if parseErr != nil {
+				unparsed = append(unparsed, f.Path)
+				continue
+			}
+			findings = append(findings, fileFindings...)
+		}
+
+		var output string
+		switch format {
+		case "json":
+			output, err = review.FormatJSON(findings)
+		case "sarif":
+			output, err = review.FormatSARIF(findings)
+		default:
+			output = review.FormatText(findings)
+		}
+		if err != nil {
+			logrus.Fatalf("格式化审查结果失败: %v", err)
+		}
+
+		outFile, _ := cmd.Flags().GetString("output")
+		if outFile != "" {
+			if err := ioutil.WriteFile(outFile, []byte(output+"\n"), 0644); err != nil {
+				logrus.Fatalf("写入文件失败: %v", err)
+			}
+			logrus.Infof("审查结果已写入 %s", outFile)
+		} else {
+			fmt.Println(output)
+		}
+
+		if len(unparsed) > 0 {
+			logrus.Errorf("以下文件的审查结果无法解析，未包含在结果中: %s", strings.Join(unparsed, ", "))
+			if gate {
+				os.Exit(1)
+			}
+		}
+		if gate && review.Exceeds(findings, threshold) {
+			logrus.Errorf("存在严重程度不低于 %s 的问题。", threshold)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(reviewCmd)
+	reviewCmd.Flags().StringP("base", "b", "", "审查当前分支相对指定分支的变更，默认审查暂存区")
+	reviewCmd.Flags().StringP("format", "f", "text", "输出格式：text、json 或 sarif")
+	reviewCmd.Flags().StringP("output", "o", "", "将结果写入指定文件")
+	reviewCmd.Flags().String("fail-on", "none", "存在不低于该严重程度的问题时以非零状态码退出：none、info、minor、major、critical")
+	reviewCmd.Flags().StringP("prompt", "t", "", "自定义提示信息，可使用 {{.File}} {{.Diff}}")
+	reviewCmd.Flags().Int("max-file-lines", 400, "单个文件保留的最大差异行数")
+	reviewCmd.Flags().Bool("allow-secrets", false, "检测到敏感信息时仍然发送（敏感内容会被屏蔽）")
+}
@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/fanook/aicli/internal/secrets"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"os"
+)
+
+// guardSecrets 在将仓库内容发送给 AI 之前检测敏感信息，按顺序返回处理后的各段文本。
+// 检测到敏感信息时默认阻止发送并返回报告；指定 --allow-secrets 时返回屏蔽后的内容。
+func guardSecrets(cmd *cobra.Command, texts ...string) ([]string, error) {
+	scanner, err := secrets.LoadScanner()
+	if err != nil {
+		return nil, err
+	}
+
+	var findings []secrets.Finding
+	masked := make([]string, len(texts))
+	for i, text := range texts {
+		m, f := scanner.Scan(text)
+		masked[i] = m
+		findings = append(findings, f...)
+	}
+	if len(findings) == 0 {
+		return texts, nil
+	}
+
+	allow, _ := cmd.Flags().GetBool("allow-secrets")
+	fmt.Fprintln(os.Stderr, secrets.Report(findings))
+	if !allow {
+		return nil, fmt.Errorf("检测到敏感信息，已阻止发送。确认无误后可使用 --allow-secrets 在屏蔽敏感内容后继续发送。")
+	}
+
+	logrus.Warn("以上敏感内容已屏蔽后发送。")
+	return masked, nil
+}
@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/fanook/aicli/internal/shellcmd"
+	"os"
+	"os/exec"
+	"os/signal"
+	"runtime"
+	"strings"
+)
+
+// userShell 返回用户的 shell，未设置 SHELL 时使用 /bin/sh
+func userShell() string {
+	if shell := os.Getenv("SHELL"); shell != "" {
+		return shell
+	}
+	return "/bin/sh"
+}
+
+// runInShell 在用户的 shell 中执行命令，输入输出直接连接到终端，返回命令的退出码
+func runInShell(command string) (int, error) {
+	var c *exec.Cmd
+	if runtime.GOOS == "windows" {
+		c = exec.Command("cmd", "/C", command)
+	} else {
+		c = exec.Command(userShell(), "-c", command)
+	}
+	c.Stdin = os.Stdin
+	c.Stdout = os.Stdout
+	c.Stderr = os.Stderr
+	return runAttached(c)
+}
+
+// runDryRun 在只读沙箱中试运行命令，返回命令的退出码
+func runDryRun(command string) (int, error) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return 1, err
+	}
+	c, err := shellcmd.DryRunCommand(userShell(), command, dir)
+	if err != nil {
+		return 1, err
+	}
+	return runAttached(c)
+}
+
+// runAttached 运行连接到终端的子进程并返回退出码。
+// 运行期间忽略 Ctrl-C，让中断信号只作用于子进程。
+func runAttached(c *exec.Cmd) (int, error) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt)
+	defer signal.Stop(signals)
+
+	err := c.Run()
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		return exitErr.ExitCode(), nil
+	}
+	if err != nil {
+		return 1, fmt.Errorf("执行命令失败: %v", err)
+	}
+	return 0, nil
+}
+
+// clipboardCommands 按平台列出可用的剪贴板命令
+var clipboardCommands = [][]string{
+	{"pbcopy"},
+	{"wl-copy"},
+	{"xclip", "-selection", "clipboard"},
+	{"xsel", "--clipboard", "--input"},
+	{"clip"},
+}
+
+// copyToClipboard 使用系统中可用的剪贴板命令复制文本
+func copyToClipboard(text string) error {
+	for _, args := range clipboardCommands {
+		if args[0] == "wl-copy" && os.Getenv("WAYLAND_DISPLAY") == "" {
+			continue
+		}
+		if !isCommandAvailable(args[0]) {
+			continue
+		}
+		c := exec.Command(args[0], args[1:]...)
+		c.Stdin = strings.NewReader(text)
+		if out, err := c.CombinedOutput(); err != nil {
+			return fmt.Errorf("%s 执行失败: %v %s", args[0], err, strings.TrimSpace(string(out)))
+		}
+		return nil
+	}
+	return fmt.Errorf("未找到可用的剪贴板工具，请安装 pbcopy、xclip、xsel 或 wl-copy")
+}
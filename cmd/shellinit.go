@@ -0,0 +1,221 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/fanook/aicli/internal/userdata"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"os"
+	"strings"
+	"text/template"
+)
+
+// shellInitScripts 是各 shell 的集成脚本，{{.Bin}} 为 aicli 的路径，{{.Key}} 为对应 shell 语法的快捷键，
+// {{.State}} 为记录上一条命令的目录，供 acl fix 读取
+var shellInitScripts = map[string]string{
+	"bash": `# aicli shell integration (bash)
+__aicli_gen_cmd() {
+  [ -z "$READLINE_LINE" ] && return
+  local result
+  result=$({{.Bin}} gen-cmd --print -- "$READLINE_LINE" </dev/null) || return
+  READLINE_LINE="$result"
+  READLINE_POINT=${#READLINE_LINE}
+}
+bind -x '"{{.Key}}": __aicli_gen_cmd'
+
+__aicli_state={{.State}}
+__aicli_record() {
+  local exit_status=$?
+{{- if .CaptureStderr}}
+  exec 2>&$__aicli_stderr
+{{- end}}
+  local entry cmd
+  entry=$(HISTTIMEFORMAT= builtin history 1)
+  # 第一次显示提示符时，历史中的是之前会话的命令，只记录编号
+  if [[ $entry =~ ^[[:space:]]*([0-9]+)\*?[[:space:]]+(.*)$ ]]; then
+    cmd=${BASH_REMATCH[2]}
+    if [ -n "$__aicli_started" ] && [ "${BASH_REMATCH[1]}" != "$__aicli_histnum" ]; then
+      case "$cmd" in
+        acl\ fix*|aicli\ fix*) ;;
+        *) printf '%s\n%s\n%s\n' "$exit_status" "$PWD" "$cmd" >"$__aicli_state/$$.cmd" ;;
+      esac
+    fi
+    __aicli_histnum=${BASH_REMATCH[1]}
+  fi
+  __aicli_started=1
+  __aicli_ready=1
+  return $exit_status
+}
+{{- if .CaptureStderr}}
+exec {__aicli_stderr}>&2
+__aicli_preexec() {
+  [ -n "$__aicli_ready" ] || return
+  [[ $BASH_COMMAND == __aicli_* ]] && return
+  __aicli_ready=
+  : >"$__aicli_state/$$.stderr"
+  exec 2> >(tee -a "$__aicli_state/$$.stderr" >&$__aicli_stderr)
+}
+trap '__aicli_preexec' DEBUG
+{{- end}}
+PROMPT_COMMAND="__aicli_record${PROMPT_COMMAND:+;$PROMPT_COMMAND}"
+`,
+	"zsh": `# aicli shell integration (zsh)
+__aicli_gen_cmd() {
+  [[ -z "$BUFFER" ]] && return
+  local result
+  zle -I
+  if result=$({{.Bin}} gen-cmd --print -- "$BUFFER" </dev/null); then
+    BUFFER="$result"
+    CURSOR=${#BUFFER}
+  fi
+  zle reset-prompt
+}
+zle -N __aicli_gen_cmd
+bindkey '{{.Key}}' __aicli_gen_cmd
+
+__aicli_state={{.State}}
+{{- if .CaptureStderr}}
+exec {__aicli_stderr}>&2
+{{- end}}
+__aicli_preexec() {
+  __aicli_cmd=$1
+{{- if .CaptureStderr}}
+  : >"$__aicli_state/$$.stderr"
+  exec 2> >(tee -a "$__aicli_state/$$.stderr" >&$__aicli_stderr)
+{{- end}}
+}
+__aicli_precmd() {
+  local exit_status=$?
+{{- if .CaptureStderr}}
+  exec 2>&$__aicli_stderr
+{{- end}}
+  [[ -z "$__aicli_cmd" ]] && return
+  case "$__aicli_cmd" in
+    acl\ fix*|aicli\ fix*) ;;
+    *) printf '%s\n%s\n%s\n' "$exit_status" "$PWD" "$__aicli_cmd" >"$__aicli_state/$$.cmd" ;;
+  esac
+  __aicli_cmd=
+}
+autoload -Uz add-zsh-hook
+add-zsh-hook preexec __aicli_preexec
+precmd_functions=(__aicli_precmd $precmd_functions)
+`,
+	"fish": `# aicli shell integration (fish)
+function __aicli_gen_cmd
+    set -l desc (commandline)
+    test -z "$desc"; and return
+    set -l result ({{.Bin}} gen-cmd --print -- "$desc" </dev/null | string collect)
+    and commandline -r -- $result
+    commandline -f repaint
+end
+bind {{.Key}} __aicli_gen_cmd
+
+set -g __aicli_state {{.State}}
+function __aicli_record --on-event fish_postexec
+    set -l exit_status $status
+    string match -qr '^(acl|aicli) fix' -- $argv[1]; and return
+    test -z "$argv[1]"; and return
+    printf '%s\n%s\n%s\n' $exit_status $PWD $argv[1] >$__aicli_state/$fish_pid.cmd
+end
+{{- if .CaptureStderr}}
+# fish 不支持重定向 shell 自身的标准错误，acl fix 将只使用命令和退出码
+{{- end}}
+`,
+}
+
+// shellInitCmd 输出 shell 集成脚本
+var shellInitCmd = &cobra.Command{
+	Use:   "shell-init <bash|zsh|fish>",
+	Short: "输出 shell 集成脚本，通过快捷键在命令行中直接生成命令",
+	Long: `输出 shell 集成脚本。加载后在命令行中输入自然语言描述，按下快捷键（默认 Ctrl-G），
+当前输入会被替换为 gen-cmd 生成的命令，可以继续编辑后回车执行。生成命令的解释和风险提示会输出到终端，
+包含极其危险操作的命令不会被填入命令行。
+
+集成脚本还会记录每条命令及其退出码，供 acl fix 分析失败原因。使用 --capture-stderr 时，
+bash 和 zsh 会通过 tee 同时记录命令的标准错误；此时命令的标准错误不再是终端，
+部分程序的彩色输出可能会失效，bash 下还会占用 DEBUG trap。`,
+	Example: `  # 添加到 ~/.bashrc
+  eval "$(acl shell-init bash)"
+  # 添加到 ~/.zshrc
+  eval "$(acl shell-init zsh)"
+  # 添加到 ~/.config/fish/config.fish
+  acl shell-init fish | source`,
+	Args:      cobra.ExactArgs(1),
+	ValidArgs: []string{"bash", "zsh", "fish"},
+	Run: func(cmd *cobra.Command, args []string) {
+		shell := args[0]
+		script, ok := shellInitScripts[shell]
+		if !ok {
+			logrus.Fatalf("不支持的 shell: %s，可选 bash、zsh、fish", shell)
+		}
+
+		keyName, _ := cmd.Flags().GetString("key")
+		key, err := shellKey(shell, keyName)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+
+		bin, err := os.Executable()
+		if err != nil {
+			bin = "aicli"
+		}
+
+		state, err := shellStateDir()
+		if err != nil {
+			logrus.Fatalf("创建状态目录失败: %v", err)
+		}
+		captureStderr, _ := cmd.Flags().GetBool("capture-stderr")
+
+		tmpl := template.Must(template.New(shell).Parse(script))
+		var buf bytes.Buffer
+		err = tmpl.Execute(&buf, struct {
+			Bin           string
+			Key           string
+			State         string
+			CaptureStderr bool
+		}{
+			Bin:           shellQuote(bin),
+			Key:           key,
+			State:         shellQuote(state),
+			CaptureStderr: captureStderr,
+		})
+		if err != nil {
+			logrus.Fatalf("生成脚本失败: %v", err)
+		}
+		fmt.Print(buf.String())
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(shellInitCmd)
+	shellInitCmd.Flags().String("key", "ctrl-g", "触发生成的快捷键，格式为 ctrl-<字母>")
+	shellInitCmd.Flags().Bool("capture-stderr", false, "通过 tee 记录每条命令的标准错误，供 acl fix 使用（仅 bash 和 zsh）")
+}
+
+// shellStateDir 返回 shell 集成记录上一条命令的目录，每个 shell 进程对应 <pid>.cmd 和 <pid>.stderr 两个文件
+func shellStateDir() (string, error) {
+	dir, err := userdata.Path("shell")
+	if err != nil {
+		return "", err
+	}
+	return dir, os.MkdirAll(dir, 0700)
+}
+
+// shellKey 将 ctrl-<字母> 转换为各 shell 的快捷键写法
+func shellKey(shell, name string) (string, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	letter := strings.TrimPrefix(name, "ctrl-")
+	if letter == name || len(letter) != 1 || letter[0] < 'a' || letter[0] > 'z' {
+		return "", fmt.Errorf("无效的快捷键 %q，格式应为 ctrl-<字母>，例如 ctrl-g", name)
+	}
+
+	switch shell {
+	case "bash":
+		return `\C-` + letter, nil
+	case "zsh":
+		return "^" + strings.ToUpper(letter), nil
+	default:
+		return `\c` + letter, nil
+	}
+}
@@ -0,0 +1,112 @@
+package changelog
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Header 是新建 CHANGELOG.md 时使用的文件头
+const Header = `# Changelog
+
+All notable changes to this project will be documented in this file.
+
+The format is based on [Keep a Changelog](https://keepachangelog.com/en/1.1.0/),
+and this project adheres to [Semantic Versioning](https://semver.org/spec/v2.0.0.html).
+`
+
+// Sections 是 Keep a Changelog 规定的分类，按输出顺序排列
+var Sections = []string{"Added", "Changed", "Deprecated", "Removed", "Fixed", "Security"}
+
+var versionHeading = regexp.MustCompile(`(?m)^## \[([^\]]+)\]`)
+var linkReference = regexp.MustCompile(`^\[[^\]]+\]:\s*\S+`)
+
+// SectionFor 将提交类型映射到 Keep a Changelog 的分类，返回空字符串表示不面向用户
+func SectionFor(commitType string, breaking bool) string {
+	if breaking {
+		return "Changed"
+	}
+	switch commitType {
+	case "feat":
+		return "Added"
+	case "fix":
+		return "Fixed"
+	case "perf", "refactor":
+		return "Changed"
+	case "security":
+		return "Security"
+	case "deprecate", "deprecated":
+		return "Deprecated"
+	case "remove", "removed":
+		return "Removed"
+	}
+	return ""
+}
+
+// Heading 返回版本标题，Unreleased 不带日期
+func Heading(version, date string) string {
+	if strings.EqualFold(version, "Unreleased") {
+		return "## [Unreleased]"
+	}
+	return fmt.Sprintf("## [%s] - %s", strings.TrimPrefix(version, "v"), date)
+}
+
+// Insert 将新版本的说明插入到已有的 CHANGELOG 内容中。
+// 同名版本已存在时替换其内容，否则插入到第一个版本之前；内容为空时使用标准文件头。
+// 文件末尾的链接引用（如 [1.0.0]: https://...）会保留在最后。
+func Insert(existing, version, date, notes string) string {
+	if strings.TrimSpace(existing) == "" {
+		existing = Header
+	}
+	section := Heading(version, date) + "\n\n" + strings.TrimSpace(notes) + "\n"
+	refs := linkReferencesStart(existing)
+
+	matches := versionHeading.FindAllStringSubmatchIndex(existing, -1)
+	name := strings.TrimPrefix(version, "v")
+	for i, m := range matches {
+		if !strings.EqualFold(existing[m[2]:m[3]], name) {
+			continue
+		}
+		end := refs
+		if i+1 < len(matches) {
+			end = matches[i+1][0]
+		}
+		rest := strings.TrimLeft(existing[end:], "\n")
+		if rest == "" {
+			return existing[:m[0]] + section
+		}
+		return existing[:m[0]] + section + "\n" + rest
+	}
+
+	// 新版本插入到 Unreleased 之后、其他版本之前
+	for _, m := range matches {
+		if strings.EqualFold(existing[m[2]:m[3]], "Unreleased") {
+			continue
+		}
+		return existing[:m[0]] + section + "\n" + existing[m[0]:]
+	}
+	result := strings.TrimRight(existing[:refs], "\n") + "\n\n" + section
+	if refs < len(existing) {
+		result += "\n" + existing[refs:]
+	}
+	return result
+}
+
+// linkReferencesStart 返回文件末尾链接引用块的起始位置，没有链接引用时返回内容长度
+func linkReferencesStart(s string) int {
+	lines := strings.SplitAfter(s, "\n")
+	start := len(s)
+	offset := len(s)
+	for i := len(lines) - 1; i >= 0; i-- {
+		offset -= len(lines[i])
+		line := strings.TrimSpace(lines[i])
+		if line == "" {
+			continue
+		}
+		if !linkReference.MatchString(line) {
+			break
+		}
+		start = offset
+	}
+	return start
+}
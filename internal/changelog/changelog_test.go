@@ -0,0 +1,110 @@
+package changelog
+
+import "testing"
+
+const existing = `# Changelog
+
+## [Unreleased]
+
+### Added
+- pending
+
+## [1.1.0] - 2026-02-01
+
+### Fixed
+- bug
+
+## [1.0.0] - 2026-01-01
+
+### Added
+- first
+
+[Unreleased]: https://example.com/compare/v1.1.0...HEAD
+[1.1.0]: https://example.com/compare/v1.0.0...v1.1.0
+[1.0.0]: https://example.com/releases/tag/v1.0.0
+`
+
+const refs = `[Unreleased]: https://example.com/compare/v1.1.0...HEAD
+[1.1.0]: https://example.com/compare/v1.0.0...v1.1.0
+[1.0.0]: https://example.com/releases/tag/v1.0.0
+`
+
+func TestInsert(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing string
+		version  string
+		want     string
+	}{
+		{
+			name:     "empty file",
+			existing: "",
+			version:  "v1.0.0",
+			want:     Header + "\n## [1.0.0] - 2026-03-01\n\n### Added\n- new\n",
+		},
+		{
+			name:     "insert new version after Unreleased",
+			existing: existing,
+			version:  "1.2.0",
+			want: "# Changelog\n\n## [Unreleased]\n\n### Added\n- pending\n\n" +
+				"## [1.2.0] - 2026-03-01\n\n### Added\n- new\n\n" +
+				"## [1.1.0] - 2026-02-01\n\n### Fixed\n- bug\n\n" +
+				"## [1.0.0] - 2026-01-01\n\n### Added\n- first\n\n" + refs,
+		},
+		{
+			name:     "replace middle version",
+			existing: existing,
+			version:  "v1.1.0",
+			want: "# Changelog\n\n## [Unreleased]\n\n### Added\n- pending\n\n" +
+				"## [1.1.0] - 2026-03-01\n\n### Added\n- new\n\n" +
+				"## [1.0.0] - 2026-01-01\n\n### Added\n- first\n\n" + refs,
+		},
+		{
+			name:     "replace last version keeps link references",
+			existing: existing,
+			version:  "1.0.0",
+			want: "# Changelog\n\n## [Unreleased]\n\n### Added\n- pending\n\n" +
+				"## [1.1.0] - 2026-02-01\n\n### Fixed\n- bug\n\n" +
+				"## [1.0.0] - 2026-03-01\n\n### Added\n- new\n\n" + refs,
+		},
+		{
+			name:     "replace last version without link references",
+			existing: "# Changelog\n\n## [1.0.0] - 2026-01-01\n\n- first\n",
+			version:  "1.0.0",
+			want:     "# Changelog\n\n## [1.0.0] - 2026-03-01\n\n### Added\n- new\n",
+		},
+		{
+			name:     "append before link references",
+			existing: "# Changelog\n\n## [Unreleased]\n\n- pending\n\n[Unreleased]: https://example.com/compare/HEAD\n",
+			version:  "1.0.0",
+			want: "# Changelog\n\n## [Unreleased]\n\n- pending\n\n" +
+				"## [1.0.0] - 2026-03-01\n\n### Added\n- new\n\n[Unreleased]: https://example.com/compare/HEAD\n",
+		},
+	}
+	for _, tt := range tests {
+		got := Insert(tt.existing, tt.version, "2026-03-01", "### Added\n- new\n")
+		if got != tt.want {
+			t.Errorf("%s: Insert() =\n%s\nwant:\n%s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSectionFor(t *testing.T) {
+	tests := []struct {
+		commitType string
+		breaking   bool
+		want       string
+	}{
+		{"feat", false, "Added"},
+		{"fix", false, "Fixed"},
+		{"refactor", false, "Changed"},
+		{"docs", true, "Changed"},
+		{"security", false, "Security"},
+		{"chore", false, ""},
+	}
+	for _, tt := range tests {
+		if got := SectionFor(tt.commitType, tt.breaking); got != tt.want {
+			t.Errorf("SectionFor(%q, %v) = %q, want %q", tt.commitType, tt.breaking, got, tt.want)
+		}
+	}
+}
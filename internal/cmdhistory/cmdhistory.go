@@ -0,0 +1,166 @@
+// Package cmdhistory 使用 SQLite 保存 gen-cmd 生成过的命令，支持搜索和收藏
+package cmdhistory
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/fanook/aicli/internal/userdata"
+	"strings"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// FileName 是历史数据库在数据目录下的文件名
+const FileName = "gencmd_history.db"
+
+const schema = `CREATE TABLE IF NOT EXISTS history (
+	id          INTEGER PRIMARY KEY AUTOINCREMENT,
+	created_at  DATETIME NOT NULL,
+	description TEXT NOT NULL,
+	response    TEXT NOT NULL,
+	command     TEXT NOT NULL,
+	explanation TEXT NOT NULL DEFAULT '',
+	executed    INTEGER NOT NULL DEFAULT 0,
+	exit_code   INTEGER,
+	starred     INTEGER NOT NULL DEFAULT 0,
+	executed_command TEXT NOT NULL DEFAULT ''
+)`
+
+// migrations 为旧版本创建的数据库补充新增的列，列已存在时忽略
+var migrations = []string{
+	`ALTER TABLE history ADD COLUMN executed_command TEXT NOT NULL DEFAULT ''`,
+}
+
+// Entry 是一条历史记录
+type Entry struct {
+	ID          int64
+	CreatedAt   time.Time
+	Description string
+	Response    string
+	// Command 是 AI 生成的命令
+	Command     string
+	Explanation string
+	Executed    bool
+	// ExecutedCommand 是实际执行的命令，编辑后执行时与 Command 不同
+	ExecutedCommand string
+	// ExitCode 仅在 Executed 为 true 时有效
+	ExitCode int
+	Starred  bool
+}
+
+// Store 是历史记录数据库
+type Store struct {
+	db *sql.DB
+}
+
+// Open 打开数据目录下的历史数据库，不存在时自动创建
+func Open() (*Store, error) {
+	path, err := userdata.Path(FileName)
+	if err != nil {
+		return nil, err
+	}
+	return OpenPath(path)
+}
+
+// OpenPath 打开指定路径的历史数据库
+func OpenPath(path string) (*Store, error) {
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, err
+	}
+	// 只使用一个连接，避免并发写入时锁冲突，也使 :memory: 数据库在各次查询间共享
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("初始化历史数据库失败: %v", err)
+	}
+	for _, m := range migrations {
+		if _, err := db.Exec(m); err != nil && !strings.Contains(err.Error(), "duplicate column name") {
+			db.Close()
+			return nil, fmt.Errorf("升级历史数据库失败: %v", err)
+		}
+	}
+	return &Store{db: db}, nil
+}
+
+// Close 关闭数据库
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Add 记录一次生成，返回记录编号
+func (s *Store) Add(description, response, command, explanation string) (int64, error) {
+	res, err := s.db.Exec(`INSERT INTO history (created_at, description, response, command, explanation) VALUES (?, ?, ?, ?, ?)`,
+		time.Now(), description, response, command, explanation)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// MarkExecuted 记录命令已执行及其退出码。command 为实际执行的命令，单独保存，生成的命令保持不变。
+func (s *Store) MarkExecuted(id int64, command string, exitCode int) error {
+	_, err := s.db.Exec(`UPDATE history SET executed = 1, executed_command = ?, exit_code = ? WHERE id = ?`, command, exitCode, id)
+	return err
+}
+
+// SetStarred 收藏或取消收藏一条记录
+func (s *Store) SetStarred(id int64, starred bool) error {
+	res, err := s.db.Exec(`UPDATE history SET starred = ? WHERE id = ?`, starred, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("记录 #%d 不存在", id)
+	}
+	return nil
+}
+
+// Get 返回指定编号的记录
+func (s *Store) Get(id int64) (Entry, error) {
+	entries, err := s.query(`WHERE id = ?`, id)
+	if err != nil {
+		return Entry{}, err
+	}
+	if len(entries) == 0 {
+		return Entry{}, fmt.Errorf("记录 #%d 不存在", id)
+	}
+	return entries[0], nil
+}
+
+// likeEscaper 转义 LIKE 中的通配符，使关键字按字面匹配
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// Search 按描述、生成的命令或实际执行的命令模糊搜索，结果按时间倒序排列
+func (s *Store) Search(keyword string, starredOnly bool, limit int) ([]Entry, error) {
+	pattern := "%" + likeEscaper.Replace(keyword) + "%"
+	return s.query(`WHERE (description LIKE ? ESCAPE '\' OR command LIKE ? ESCAPE '\' OR executed_command LIKE ? ESCAPE '\')
+		AND (starred = 1 OR ? = 0) ORDER BY id DESC LIMIT ?`,
+		pattern, pattern, pattern, starredOnly, limit)
+}
+
+// Favorites 返回最近收藏的记录，用作生成命令时的示例
+func (s *Store) Favorites(limit int) ([]Entry, error) {
+	return s.query(`WHERE starred = 1 ORDER BY id DESC LIMIT ?`, limit)
+}
+
+func (s *Store) query(where string, args ...interface{}) ([]Entry, error) {
+	rows, err := s.db.Query(`SELECT id, created_at, description, response, command, explanation, executed, exit_code, starred, executed_command FROM history `+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []Entry
+	for rows.Next() {
+		var e Entry
+		var exitCode sql.NullInt64
+		if err := rows.Scan(&e.ID, &e.CreatedAt, &e.Description, &e.Response, &e.Command, &e.Explanation, &e.Executed, &exitCode, &e.Starred, &e.ExecutedCommand); err != nil {
+			return nil, err
+		}
+		e.ExitCode = int(exitCode.Int64)
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
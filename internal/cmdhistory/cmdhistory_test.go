@@ -0,0 +1,180 @@
+package cmdhistory
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+)
+
+func openMemory(t *testing.T) *Store {
+	t.Helper()
+	s, err := OpenPath(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func add(t *testing.T, s *Store, description, command string) int64 {
+	t.Helper()
+	id, err := s.Add(description, "CMD: "+command, command, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+func TestAddAndMarkExecuted(t *testing.T) {
+	s := openMemory(t)
+	id, err := s.Add("列出文件", "CMD: ls", "ls", "列出当前目录")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e, err := s.Get(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Description != "列出文件" || e.Command != "ls" || e.Explanation != "列出当前目录" || e.Executed || e.ExecutedCommand != "" {
+		t.Errorf("Get() = %+v", e)
+	}
+
+	if err := s.MarkExecuted(id, "ls -la", 2); err != nil {
+		t.Fatal(err)
+	}
+	e, _ = s.Get(id)
+	if !e.Executed || e.ExitCode != 2 || e.ExecutedCommand != "ls -la" || e.Command != "ls" {
+		t.Errorf("after MarkExecuted: %+v, want generated command kept", e)
+	}
+
+	if _, err := s.Get(id + 1); err == nil {
+		t.Error("Get() of a missing record should fail")
+	}
+}
+
+func TestStarredAndFavorites(t *testing.T) {
+	s := openMemory(t)
+	first := add(t, s, "a", "echo a")
+	second := add(t, s, "b", "echo b")
+	add(t, s, "c", "echo c")
+
+	for _, id := range []int64{first, second} {
+		if err := s.SetStarred(id, true); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.MarkExecuted(second, "echo edited", 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetStarred(99, true); err == nil {
+		t.Error("SetStarred() of a missing record should fail")
+	}
+
+	favorites, err := s.Favorites(5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(favorites) != 2 || favorites[0].ID != second || favorites[1].ID != first {
+		t.Fatalf("Favorites() = %+v", favorites)
+	}
+	// 作为示例使用的是生成的命令
+	if favorites[0].Command != "echo b" {
+		t.Errorf("Favorites()[0].Command = %q, want the generated command", favorites[0].Command)
+	}
+
+	if err := s.SetStarred(first, false); err != nil {
+		t.Fatal(err)
+	}
+	if favorites, _ := s.Favorites(5); len(favorites) != 1 {
+		t.Errorf("Favorites() after unstar = %+v", favorites)
+	}
+	if favorites, _ := s.Favorites(1); len(favorites) != 1 || favorites[0].ID != second {
+		t.Errorf("Favorites(1) = %+v", favorites)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	s := openMemory(t)
+	percent := add(t, s, "磁盘使用率 100%", "df -h")
+	underscore := add(t, s, "查找文件", "find . -name 'a_b'")
+	other := add(t, s, "查找 axb", "ls axb")
+	backslash := add(t, s, `转义 a\b`, "echo")
+	edited := add(t, s, "打包", "tar czf out.tgz .")
+	if err := s.MarkExecuted(edited, "tar czf backup.tgz src", 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetStarred(other, true); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		keyword string
+		starred bool
+		want    []int64
+	}{
+		{"", false, []int64{edited, backslash, other, underscore, percent}},
+		{"%", false, []int64{percent}},
+		{"a_b", false, []int64{underscore}},
+		{"_", false, []int64{underscore}},
+		{`a\b`, false, []int64{backslash}},
+		{"backup", false, []int64{edited}},
+		{"查找", false, []int64{other, underscore}},
+		{"查找", true, []int64{other}},
+		{"nothing", false, nil},
+	}
+	for _, tt := range tests {
+		entries, err := s.Search(tt.keyword, tt.starred, 10)
+		if err != nil {
+			t.Errorf("Search(%q): %v", tt.keyword, err)
+			continue
+		}
+		var got []int64
+		for _, e := range entries {
+			got = append(got, e.ID)
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("Search(%q, %v) = %v, want %v", tt.keyword, tt.starred, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("Search(%q, %v) = %v, want %v", tt.keyword, tt.starred, got, tt.want)
+				break
+			}
+		}
+	}
+
+	if entries, _ := s.Search("", false, 2); len(entries) != 2 {
+		t.Errorf("Search() with limit 2 returned %d entries", len(entries))
+	}
+}
+
+func TestMigrateOldSchema(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.Exec(`CREATE TABLE history (
+		id INTEGER PRIMARY KEY AUTOINCREMENT, created_at DATETIME NOT NULL, description TEXT NOT NULL,
+		response TEXT NOT NULL, command TEXT NOT NULL, explanation TEXT NOT NULL DEFAULT '',
+		executed INTEGER NOT NULL DEFAULT 0, exit_code INTEGER, starred INTEGER NOT NULL DEFAULT 0);
+		INSERT INTO history (created_at, description, response, command) VALUES (datetime('now'), 'old', '', 'ls')`)
+	db.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		s, err := OpenPath(path)
+		if err != nil {
+			t.Fatalf("OpenPath() #%d: %v", i+1, err)
+		}
+		e, err := s.Get(1)
+		if err != nil || e.Command != "ls" || e.ExecutedCommand != "" {
+			t.Errorf("Get() after migration = %+v, %v", e, err)
+		}
+		s.Close()
+	}
+}
@@ -0,0 +1,165 @@
+// Package cmdsuggest 解析 gen-cmd 中 AI 返回的命令建议
+package cmdsuggest
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Suggestion 是一条建议执行的命令及其解释。Portability 和 Tools 只在生成多个备选方案时提供。
+type Suggestion struct {
+	Command     string   `json:"command"`
+	Explanation string   `json:"explanation"`
+	Portability string   `json:"portability,omitempty"`
+	Tools       []string `json:"tools,omitempty"`
+}
+
+var (
+	commandPrefixes     = []string{"CMD:", "CMD：", "命令:", "命令："}
+	explanationPrefixes = []string{"解释:", "解释：", "说明:", "说明："}
+)
+
+// Parse 解析 "CMD: ... 解释: ..." 格式的回复，支持多组命令。
+// 命令或解释跨多行时，后续行会追加到当前字段；回复中没有 CMD 标记时，退而使用第一个代码块。
+func Parse(response string) []Suggestion {
+	var suggestions []Suggestion
+	var current *Suggestion
+	inExplanation := false
+	inFence := false
+
+	for _, line := range strings.Split(response, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") {
+			inFence = !inFence
+			continue
+		}
+
+		if rest, ok := cutPrefix(trimmed, commandPrefixes); ok {
+			suggestions = append(suggestions, Suggestion{})
+			current = &suggestions[len(suggestions)-1]
+			inExplanation = false
+			// 部分模型会把解释写在同一行
+			if cmdPart, explanation, found := cutAny(rest, explanationPrefixes); found {
+				current.Command = cleanCommand(cmdPart)
+				current.Explanation = strings.TrimSpace(explanation)
+				inExplanation = true
+				continue
+			}
+			current.Command = cleanCommand(rest)
+			continue
+		}
+
+		if current == nil {
+			continue
+		}
+
+		if rest, ok := cutPrefix(trimmed, explanationPrefixes); ok {
+			current.Explanation = strings.TrimSpace(rest)
+			inExplanation = true
+			continue
+		}
+
+		if trimmed == "" {
+			continue
+		}
+		if inExplanation {
+			current.Explanation = strings.TrimSpace(current.Explanation + "\n" + trimmed)
+		} else if inFence || current.Command == "" || strings.HasSuffix(current.Command, "\\") {
+			current.Command = strings.TrimSpace(current.Command + "\n" + cleanCommand(line))
+		}
+	}
+
+	var result []Suggestion
+	for _, s := range suggestions {
+		if s.Command != "" {
+			result = append(result, s)
+		}
+	}
+	if len(result) == 0 {
+		if block := firstCodeBlock(response); block != "" {
+			result = append(result, Suggestion{Command: block})
+		}
+	}
+	return result
+}
+
+// cleanCommand 去掉命令两侧的空白、反引号和提示符
+func cleanCommand(s string) string {
+	s = strings.TrimSpace(s)
+	s = strings.Trim(s, "`")
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "$ ") {
+		s = s[2:]
+	}
+	return s
+}
+
+func cutPrefix(s string, prefixes []string) (string, bool) {
+	s = strings.TrimLeft(s, "- ")
+	for _, p := range prefixes {
+		// 兼容 **CMD**: 这类 Markdown 加粗写法，需要在去掉 * 之前判断
+		bold := "**" + strings.TrimRight(p, ":：") + "**"
+		if rest := strings.TrimPrefix(s, bold); rest != s && (strings.HasPrefix(rest, ":") || strings.HasPrefix(rest, "：")) {
+			return strings.TrimLeft(rest, ":：* "), true
+		}
+	}
+	s = strings.TrimLeft(s, "*- ")
+	for _, p := range prefixes {
+		if strings.HasPrefix(s, p) {
+			return strings.TrimLeft(s[len(p):], "* "), true
+		}
+	}
+	return "", false
+}
+
+func cutAny(s string, seps []string) (string, string, bool) {
+	for _, sep := range seps {
+		if i := strings.Index(s, sep); i >= 0 {
+			return s[:i], s[i+len(sep):], true
+		}
+	}
+	return s, "", false
+}
+
+func firstCodeBlock(s string) string {
+	start := strings.Index(s, "```")
+	if start < 0 {
+		return ""
+	}
+	rest := s[start+3:]
+	if nl := strings.Index(rest, "\n"); nl >= 0 {
+		rest = rest[nl+1:]
+	}
+	end := strings.Index(rest, "```")
+	if end < 0 {
+		return ""
+	}
+	return strings.TrimSpace(rest[:end])
+}
+
+// ParseJSON 解析 JSON 数组格式的备选方案，忽略数组前后的多余内容
+func ParseJSON(response string) ([]Suggestion, error) {
+	start := strings.Index(response, "[")
+	end := strings.LastIndex(response, "]")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("回复中没有找到 JSON 数组")
+	}
+
+	var suggestions []Suggestion
+	if err := json.Unmarshal([]byte(response[start:end+1]), &suggestions); err != nil {
+		return nil, fmt.Errorf("解析备选方案失败: %v", err)
+	}
+
+	var result []Suggestion
+	for _, s := range suggestions {
+		s.Command = cleanCommand(s.Command)
+		if s.Command != "" {
+			result = append(result, s)
+		}
+	}
+	if len(result) == 0 {
+		return nil, fmt.Errorf("回复中没有可用的命令")
+	}
+	return result, nil
+}
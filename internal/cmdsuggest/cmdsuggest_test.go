@@ -0,0 +1,111 @@
+package cmdsuggest
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		want     []Suggestion
+	}{
+		{
+			name:     "separate lines",
+			response: "CMD: ls -la\n解释: 列出所有文件",
+			want:     []Suggestion{{Command: "ls -la", Explanation: "列出所有文件"}},
+		},
+		{
+			name:     "explanation on the same line",
+			response: "CMD: `du -sh *` 解释：统计目录大小",
+			want:     []Suggestion{{Command: "du -sh *", Explanation: "统计目录大小"}},
+		},
+		{
+			name:     "bold markers",
+			response: "**CMD**: `df -h`\n**解释**: 查看磁盘空间\n",
+			want:     []Suggestion{{Command: "df -h", Explanation: "查看磁盘空间"}},
+		},
+		{
+			name:     "chinese labels and list markers",
+			response: "- 命令：pwd\n- 说明：显示当前目录",
+			want:     []Suggestion{{Command: "pwd", Explanation: "显示当前目录"}},
+		},
+		{
+			name:     "fenced multi-line command",
+			response: "CMD:\n```bash\nfor f in *.txt; do\n  wc -l \"$f\"\ndone\n```\n解释: 统计每个文件的行数",
+			want:     []Suggestion{{Command: "for f in *.txt; do\nwc -l \"$f\"\ndone", Explanation: "统计每个文件的行数"}},
+		},
+		{
+			name:     "backslash continuation",
+			response: "CMD: docker run \\\n  -p 80:80 \\\n  nginx\n解释: 启动 nginx",
+			want:     []Suggestion{{Command: "docker run \\\n-p 80:80 \\\nnginx", Explanation: "启动 nginx"}},
+		},
+		{
+			name:     "multi-line explanation and multiple steps",
+			response: "CMD: $ mkdir out\n解释: 创建目录\n用于保存结果\n\nCMD: cp *.log out/\n解释: 复制日志",
+			want: []Suggestion{
+				{Command: "mkdir out", Explanation: "创建目录\n用于保存结果"},
+				{Command: "cp *.log out/", Explanation: "复制日志"},
+			},
+		},
+		{
+			name:     "text after a single-line command is ignored",
+			response: "CMD: uptime\n这条命令很常用",
+			want:     []Suggestion{{Command: "uptime"}},
+		},
+		{
+			name:     "first code block fallback",
+			response: "可以使用下面的命令：\n```sh\nfind . -name '*.go'\n```\n或者：\n```sh\nls\n```",
+			want:     []Suggestion{{Command: "find . -name '*.go'"}},
+		},
+		{
+			name:     "no command",
+			response: "抱歉，我无法理解这个需求。",
+			want:     nil,
+		},
+	}
+	for _, tt := range tests {
+		if got := Parse(tt.response); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: Parse() = %#v, want %#v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestParseJSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		want     []Suggestion
+		wantErr  bool
+	}{
+		{
+			name:     "plain array",
+			response: `[{"command": "ls", "explanation": "list", "portability": "POSIX", "tools": ["ls"]}]`,
+			want:     []Suggestion{{Command: "ls", Explanation: "list", Portability: "POSIX", Tools: []string{"ls"}}},
+		},
+		{
+			name:     "surrounded by prose and fences",
+			response: "以下是两种方案：\n```json\n[{\"command\": \"`$ ls -1`\"}, {\"command\": \"find . -maxdepth 1\"}]\n```\n请根据系统选择。",
+			want:     []Suggestion{{Command: "ls -1"}, {Command: "find . -maxdepth 1"}},
+		},
+		{
+			name:     "empty commands dropped",
+			response: `[{"command": " "}, {"command": "pwd"}]`,
+			want:     []Suggestion{{Command: "pwd"}},
+		},
+		{name: "no array", response: "CMD: ls", wantErr: true},
+		{name: "invalid json", response: `[{"command": }]`, wantErr: true},
+		{name: "only empty commands", response: `[{"command": ""}]`, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseJSON(tt.response)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: ParseJSON() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: ParseJSON() = %#v, want %#v", tt.name, got, tt.want)
+		}
+	}
+}
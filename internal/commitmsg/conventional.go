@@ -0,0 +1,195 @@
+package commitmsg
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// ConventionalTypes 是 Conventional Commits 模式下允许的提交类型
+var ConventionalTypes = []string{"feat", "fix", "refactor", "docs", "test", "chore", "perf", "build", "ci"}
+
+// MaxHeaderLength 是标题行的最大长度
+const MaxHeaderLength = 72
+
+// BodyWidth 是正文折行的宽度
+const BodyWidth = 72
+
+var headerPattern = regexp.MustCompile(`^([a-z]+)(\(([\w./-]+)\))?(!)?: (.+)$`)
+
+var footerPattern = regexp.MustCompile(`^(BREAKING CHANGE|BREAKING-CHANGE|[\w-]+): .+$`)
+
+// Header 是解析后的 Conventional Commits 标题行
+type Header struct {
+	Type     string
+	Scope    string
+	Breaking bool
+	Subject  string
+}
+
+// ParseHeader 解析 Conventional Commits 标题行
+func ParseHeader(line string) (Header, bool) {
+	m := headerPattern.FindStringSubmatch(strings.TrimSpace(line))
+	if m == nil {
+		return Header{}, false
+	}
+	return Header{Type: m[1], Scope: m[3], Breaking: m[4] == "!", Subject: m[5]}, true
+}
+
+// ValidateConventional 校验提交信息是否符合 Conventional Commits 规范
+func ValidateConventional(msg string) error {
+	lines := strings.Split(strings.TrimSpace(msg), "\n")
+	var problems []string
+
+	header, ok := ParseHeader(lines[0])
+	if !ok {
+		problems = append(problems, fmt.Sprintf("标题行必须为 \"<类型>(<范围>): <描述>\" 格式，实际为 %q", lines[0]))
+	} else {
+		if !isConventionalType(header.Type) {
+			problems = append(problems, fmt.Sprintf("类型 %q 不在允许范围内 (%s)", header.Type, strings.Join(ConventionalTypes, "/")))
+		}
+		if strings.HasSuffix(header.Subject, ".") || strings.HasSuffix(header.Subject, "。") {
+			problems = append(problems, "描述末尾不应有句号")
+		}
+	}
+	if n := len([]rune(lines[0])); n > MaxHeaderLength {
+		problems = append(problems, fmt.Sprintf("标题行长度为 %d，超过 %d 个字符", n, MaxHeaderLength))
+	}
+
+	if len(lines) > 1 && strings.TrimSpace(lines[1]) != "" {
+		problems = append(problems, "标题行与正文之间必须有一个空行")
+	}
+
+	for _, line := range lines[1:] {
+		if strings.Contains(line, "BREAKING CHANGE") && !footerPattern.MatchString(line) {
+			problems = append(problems, "破坏性变更必须以 \"BREAKING CHANGE: <说明>\" 的脚注形式给出")
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%s", strings.Join(problems, "；"))
+	}
+	return nil
+}
+
+// Normalize 去除 AI 回复中多余的代码块标记，并将正文按 BodyWidth 折行，脚注保持原样
+func Normalize(msg string) string {
+	msg = strings.TrimSpace(msg)
+	msg = strings.TrimPrefix(msg, "```text")
+	msg = strings.TrimPrefix(msg, "```")
+	msg = strings.TrimSuffix(msg, "```")
+	lines := strings.Split(strings.TrimSpace(msg), "\n")
+
+	out := []string{strings.TrimSpace(lines[0])}
+	var paragraph []string
+	flush := func() {
+		if len(paragraph) > 0 {
+			out = append(out, wrap(strings.Join(paragraph, " "), BodyWidth)...)
+			paragraph = nil
+		}
+	}
+
+	for _, line := range lines[1:] {
+		line = strings.TrimRight(line, " \t")
+		switch {
+		case strings.TrimSpace(line) == "":
+			flush()
+			out = append(out, "")
+		case footerPattern.MatchString(line), strings.HasPrefix(strings.TrimSpace(line), "- "):
+			flush()
+			out = append(out, line)
+		default:
+			paragraph = append(paragraph, strings.TrimSpace(line))
+		}
+	}
+	flush()
+
+	return strings.Join(out, "\n")
+}
+
+// InferScope 根据变更文件所在的包推断 scope，文件分布在多个包时返回空字符串
+func InferScope(paths []string) string {
+	scope := ""
+	for _, p := range paths {
+		dir := path.Dir(p)
+		if dir == "." {
+			return ""
+		}
+		s := path.Base(dir)
+		if scope != "" && s != scope {
+			return ""
+		}
+		scope = s
+	}
+	return scope
+}
+
+func isConventionalType(t string) bool {
+	for _, ct := range ConventionalTypes {
+		if ct == t {
+			return true
+		}
+	}
+	return false
+}
+
+// wrap 按宽度对一段文本折行，中文等无空格文本按字符折行
+func wrap(text string, width int) []string {
+	var lines []string
+	var current []rune
+	for _, word := range strings.Fields(text) {
+		w := []rune(word)
+		if len(current) > 0 && len(current)+1+len(w) > width {
+			lines = append(lines, string(current))
+			current = nil
+		}
+		if len(current) > 0 {
+			current = append(current, ' ')
+		}
+		current = append(current, w...)
+		for len(current) > width {
+			lines = append(lines, string(current[:width]))
+			current = current[width:]
+		}
+	}
+	if len(current) > 0 {
+		lines = append(lines, string(current))
+	}
+	return lines
+}
+
+// 提交规范名称，可通过 AICLI_COMMIT_CONVENTION 配置
+const (
+	ConventionBracket      = "bracket"
+	ConventionConventional = "conventional"
+)
+
+var bracketHeaderPattern = regexp.MustCompile(`^\[(feat|fix)\] \S.*$`)
+
+// ValidateBracket 校验提交信息是否符合 git-cmt 默认的 "[feat|fix] 描述" 格式
+func ValidateBracket(msg string) error {
+	lines := strings.Split(strings.TrimSpace(msg), "\n")
+	var problems []string
+	if !bracketHeaderPattern.MatchString(lines[0]) {
+		problems = append(problems, fmt.Sprintf("标题行必须为 \"[feat] 描述\" 或 \"[fix] 描述\" 格式，实际为 %q", lines[0]))
+	}
+	if n := len([]rune(lines[0])); n > MaxHeaderLength {
+		problems = append(problems, fmt.Sprintf("标题行长度为 %d，超过 %d 个字符", n, MaxHeaderLength))
+	}
+	if len(lines) > 1 && strings.TrimSpace(lines[1]) != "" {
+		problems = append(problems, "标题行与正文之间必须有一个空行")
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%s", strings.Join(problems, "；"))
+	}
+	return nil
+}
+
+// Validate 按指定规范校验提交信息
+func Validate(msg, convention string) error {
+	if convention == ConventionConventional {
+		return ValidateConventional(msg)
+	}
+	return ValidateBracket(msg)
+}
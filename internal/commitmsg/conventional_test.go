@@ -0,0 +1,148 @@
+package commitmsg
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateConventional(t *testing.T) {
+	long := "feat: " + strings.Repeat("a", MaxHeaderLength-len("feat: "))
+	tests := []struct {
+		name string
+		msg  string
+		ok   bool
+	}{
+		{"type only", "feat: add login", true},
+		{"with scope", "fix(api/v2): handle nil body", true},
+		{"breaking with bang", "refactor(core)!: drop legacy config", true},
+		{"breaking footer", "feat: new config\n\nBody text.\n\nBREAKING CHANGE: old keys are ignored", true},
+		{"hyphenated breaking footer", "feat: x\n\nBREAKING-CHANGE: y", true},
+		{"header at max length", long, true},
+		{"chinese subject", "docs: 更新安装说明", true},
+		{"unknown type", "feature: add login", false},
+		{"uppercase type", "Feat: add login", false},
+		{"missing colon space", "feat:add login", false},
+		{"bracket style", "[feat] add login", false},
+		{"trailing period", "fix: handle nil.", false},
+		{"trailing chinese period", "fix: 修复空指针。", false},
+		{"header too long", long + "a", false},
+		{"missing blank line", "feat: x\nbody", false},
+		{"inline breaking change", "feat: x\n\nthis is a BREAKING CHANGE for users", false},
+	}
+	for _, tt := range tests {
+		if err := ValidateConventional(tt.msg); (err == nil) != tt.ok {
+			t.Errorf("%s: ValidateConventional(%q) = %v, want ok=%v", tt.name, tt.msg, err, tt.ok)
+		}
+	}
+	for _, typ := range ConventionalTypes {
+		if err := ValidateConventional(typ + ": x"); err != nil {
+			t.Errorf("type %s rejected: %v", typ, err)
+		}
+	}
+}
+
+func TestParseHeader(t *testing.T) {
+	h, ok := ParseHeader("  feat(ui)!: dark mode ")
+	if !ok || h != (Header{Type: "feat", Scope: "ui", Breaking: true, Subject: "dark mode"}) {
+		t.Errorf("ParseHeader() = %+v, %v", h, ok)
+	}
+	if _, ok := ParseHeader("feat(): x"); ok {
+		t.Error("ParseHeader should reject an empty scope")
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	longWords := strings.TrimSpace(strings.Repeat("word ", 20))
+	tests := []struct {
+		name string
+		msg  string
+		want string
+	}{
+		{"code fence", "```\nfeat: x\n```", "feat: x"},
+		{"text fence", "```text\n  fix(api): y  \n```\n", "fix(api): y"},
+		{"bracket header", "```\n[feat] 添加登录\n```", "[feat] 添加登录"},
+		{
+			name: "body is rewrapped",
+			msg:  "feat: x\n\n" + longWords,
+			want: "feat: x\n\n" + strings.TrimSpace(strings.Repeat("word ", 14)) + "\n" + strings.TrimSpace(strings.Repeat("word ", 6)),
+		},
+		{
+			name: "short lines are joined",
+			msg:  "feat: x\n\nfirst line\nsecond line",
+			want: "feat: x\n\nfirst line second line",
+		},
+		{
+			name: "footers and list items kept",
+			msg:  "feat: x\n\n- one\n- two\n\nBREAKING CHANGE: " + longWords + "\nRefs: #12",
+			want: "feat: x\n\n- one\n- two\n\nBREAKING CHANGE: " + longWords + "\nRefs: #12",
+		},
+		{
+			name: "chinese text wrapped by rune",
+			msg:  "fix: y\n\n" + strings.Repeat("中", BodyWidth+3),
+			want: "fix: y\n\n" + strings.Repeat("中", BodyWidth) + "\n中中中",
+		},
+	}
+	for _, tt := range tests {
+		if got := Normalize(tt.msg); got != tt.want {
+			t.Errorf("%s: Normalize() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestInferScope(t *testing.T) {
+	tests := []struct {
+		paths []string
+		want  string
+	}{
+		{[]string{"internal/review/review.go", "internal/review/sarif.go"}, "review"},
+		{[]string{"cmd/a.go"}, "cmd"},
+		{[]string{"internal/review/review.go", "cmd/review.go"}, ""},
+		{[]string{"README.md", "cmd/a.go"}, ""},
+		{nil, ""},
+	}
+	for _, tt := range tests {
+		if got := InferScope(tt.paths); got != tt.want {
+			t.Errorf("InferScope(%v) = %q, want %q", tt.paths, got, tt.want)
+		}
+	}
+}
+
+func TestValidateBracket(t *testing.T) {
+	tests := []struct {
+		msg string
+		ok  bool
+	}{
+		{"[feat] 添加用户个人资料页面", true},
+		{"[fix] handle nil\n\nbody", true},
+		{"[docs] update readme", false},
+		{"[FEAT] add", false},
+		{"[feat]add", false},
+		{"feat: add", false},
+		{"[fix] x\nbody", false},
+		{"[fix] " + strings.Repeat("长", MaxHeaderLength), false},
+	}
+	for _, tt := range tests {
+		if err := ValidateBracket(tt.msg); (err == nil) != tt.ok {
+			t.Errorf("ValidateBracket(%q) = %v, want ok=%v", tt.msg, err, tt.ok)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		msg        string
+		convention string
+		ok         bool
+	}{
+		{"feat: x", ConventionConventional, true},
+		{"[feat] x", ConventionConventional, false},
+		{"[feat] x", ConventionBracket, true},
+		{"feat: x", ConventionBracket, false},
+		{"[feat] x", "", true},
+	}
+	for _, tt := range tests {
+		if err := Validate(tt.msg, tt.convention); (err == nil) != tt.ok {
+			t.Errorf("Validate(%q, %q) = %v, want ok=%v", tt.msg, tt.convention, err, tt.ok)
+		}
+	}
+}
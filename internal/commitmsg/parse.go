@@ -0,0 +1,42 @@
+package commitmsg
+
+import (
+	"regexp"
+	"strings"
+)
+
+var bracketPattern = regexp.MustCompile(`^\[([A-Za-z]+)\]\s*(.+)$`)
+
+// Parsed 是从提交标题中解析出的类型信息
+type Parsed struct {
+	Type        string
+	Scope       string
+	Breaking    bool
+	Description string
+}
+
+// Parse 解析提交标题，同时支持 git-cmt 默认的 "[feat] 描述" 格式与 Conventional Commits 格式。
+// 无法识别类型时 Type 为空，Description 为原标题。
+func Parse(subject string) Parsed {
+	subject = strings.TrimSpace(subject)
+	if h, ok := ParseHeader(subject); ok {
+		return Parsed{Type: h.Type, Scope: h.Scope, Breaking: h.Breaking, Description: h.Subject}
+	}
+	if m := bracketPattern.FindStringSubmatch(subject); m != nil {
+		return Parsed{Type: strings.ToLower(m[1]), Description: m[2]}
+	}
+	return Parsed{Description: subject}
+}
+
+// IsBreaking 判断提交是否包含不兼容变更
+func IsBreaking(subject, body string) bool {
+	if Parse(subject).Breaking {
+		return true
+	}
+	for _, line := range strings.Split(body, "\n") {
+		if strings.HasPrefix(line, "BREAKING CHANGE:") || strings.HasPrefix(line, "BREAKING-CHANGE:") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,38 @@
+package commitmsg
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		subject string
+		want    Parsed
+	}{
+		{"feat(api)!: drop v1", Parsed{Type: "feat", Scope: "api", Breaking: true, Description: "drop v1"}},
+		{"[feat] 添加登录", Parsed{Type: "feat", Description: "添加登录"}},
+		{" [FIX]  修复崩溃 ", Parsed{Type: "fix", Description: "修复崩溃"}},
+		{"Merge branch 'main'", Parsed{Description: "Merge branch 'main'"}},
+	}
+	for _, tt := range tests {
+		if got := Parse(tt.subject); got != tt.want {
+			t.Errorf("Parse(%q) = %+v, want %+v", tt.subject, got, tt.want)
+		}
+	}
+}
+
+func TestIsBreaking(t *testing.T) {
+	tests := []struct {
+		subject, body string
+		want          bool
+	}{
+		{"feat!: x", "", true},
+		{"feat: x", "text\n\nBREAKING CHANGE: y", true},
+		{"feat: x", "BREAKING-CHANGE: y", true},
+		{"feat: x", "mentions BREAKING CHANGE: inline", false},
+		{"[feat] x", "", false},
+	}
+	for _, tt := range tests {
+		if got := IsBreaking(tt.subject, tt.body); got != tt.want {
+			t.Errorf("IsBreaking(%q, %q) = %v, want %v", tt.subject, tt.body, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package commitmsg
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sort"
+
+	"github.com/fanook/aicli/internal/userdata"
+)
+
+// Style 描述一种 commit 信息的风格
+type Style struct {
+	Name        string
+	Instruction string
+	// ConventionalInstruction 非空时在 Conventional Commits 模式下代替 Instruction，
+	// 用于与该模式要求的正文等格式保持一致
+	ConventionalInstruction string
+}
+
+// InstructionFor 返回指定模式下的风格要求
+func (s Style) InstructionFor(conventional bool) string {
+	if conventional && s.ConventionalInstruction != "" {
+		return s.ConventionalInstruction
+	}
+	return s.Instruction
+}
+
+// Styles 是生成多个候选时轮流使用的风格
+var Styles = []Style{
+	{Name: "concise", Instruction: "简洁：只输出一行标题，概括最主要的改动", ConventionalInstruction: "简洁：标题概括最主要的改动，正文只用一两句话说明原因"},
+	{Name: "detailed", Instruction: "详细：标题行之后空一行，用要点列出每一处主要改动"},
+	{Name: "granular", Instruction: "细粒度：标题精确写出改动涉及的具体函数、模块或文件"},
+	{Name: "intent", Instruction: "概括：从功能和目的层面描述这次改动，不罗列实现细节"},
+}
+
+const preferenceFile = "commit_styles.json"
+
+// Preferences 记录用户选择各风格的次数
+type Preferences map[string]int
+
+// LoadPreferences 读取本地保存的风格偏好，文件不存在时返回空偏好
+func LoadPreferences() (Preferences, error) {
+	prefs := Preferences{}
+	p, err := userdata.Path(preferenceFile)
+	if err != nil {
+		return prefs, err
+	}
+	data, err := ioutil.ReadFile(p)
+	if os.IsNotExist(err) {
+		return prefs, nil
+	}
+	if err != nil {
+		return prefs, err
+	}
+	err = json.Unmarshal(data, &prefs)
+	// 文件内容为 null 时 prefs 会被置为 nil，Record 写入 nil map 会 panic
+	if prefs == nil {
+		prefs = Preferences{}
+	}
+	return prefs, err
+}
+
+// Record 记录一次风格选择并保存
+func (p Preferences) Record(style string) error {
+	p[style]++
+	path, err := userdata.Path(preferenceFile)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0600)
+}
+
+// Favorite 返回被选择次数最多的风格，没有记录时返回 false
+func (p Preferences) Favorite() (Style, bool) {
+	ordered := p.Ordered()
+	if p[ordered[0].Name] == 0 {
+		return Style{}, false
+	}
+	return ordered[0], true
+}
+
+// Ordered 按选择次数从多到少返回所有风格
+func (p Preferences) Ordered() []Style {
+	styles := append([]Style(nil), Styles...)
+	sort.SliceStable(styles, func(i, j int) bool {
+		return p[styles[i].Name] > p[styles[j].Name]
+	})
+	return styles
+}
@@ -0,0 +1,45 @@
+package commitmsg
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestPreferencesNullFile(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("AICLI_HOME", dir)
+	if err := ioutil.WriteFile(filepath.Join(dir, preferenceFile), []byte("null"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	prefs, err := LoadPreferences()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := prefs.Record("detailed"); err != nil {
+		t.Fatal(err)
+	}
+
+	prefs, err = LoadPreferences()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if style, ok := prefs.Favorite(); !ok || style.Name != "detailed" {
+		t.Errorf("Favorite() = %+v, %v, want detailed", style, ok)
+	}
+}
+
+func TestInstructionFor(t *testing.T) {
+	for _, s := range Styles {
+		if s.InstructionFor(false) != s.Instruction {
+			t.Errorf("%s: InstructionFor(false) should return Instruction", s.Name)
+		}
+		if s.InstructionFor(true) == "" {
+			t.Errorf("%s: InstructionFor(true) is empty", s.Name)
+		}
+	}
+	if got := Styles[0].InstructionFor(true); got == Styles[0].Instruction {
+		t.Errorf("concise style should not ask for a title-only message in conventional mode: %q", got)
+	}
+}
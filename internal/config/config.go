@@ -0,0 +1,53 @@
+// Package config 读取仓库根目录下的 .aicli.yaml 配置文件
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FileName 是仓库配置文件的文件名
+const FileName = ".aicli.yaml"
+
+// Config 是仓库级别的配置，未知的配置项会被忽略
+type Config struct {
+	// Lang 是生成内容使用的语言，例如 zh、en
+	Lang string `yaml:"lang"`
+}
+
+// Parse 解析 YAML 格式的配置内容，空内容返回零值配置
+func Parse(content []byte) (Config, error) {
+	var c Config
+	if err := yaml.Unmarshal(content, &c); err != nil {
+		return Config{}, err
+	}
+	c.Lang = strings.TrimSpace(c.Lang)
+	return c, nil
+}
+
+// Load 读取并解析配置文件，文件不存在时返回零值配置
+func Load(path string) (Config, error) {
+	content, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return Config{}, nil
+	}
+	if err != nil {
+		return Config{}, err
+	}
+
+	c, err := Parse(content)
+	if err != nil {
+		return Config{}, fmt.Errorf("%s: %v", path, err)
+	}
+	return c, nil
+}
+
+// LoadRepo 读取仓库根目录 dir 下的 .aicli.yaml
+func LoadRepo(dir string) (Config, error) {
+	return Load(filepath.Join(dir, FileName))
+}
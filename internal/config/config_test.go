@@ -0,0 +1,54 @@
+package config
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"flat", "lang: en\n", "en"},
+		{"quoted with comment", "lang: \"zh-TW\" # 繁体\n", "zh-TW"},
+		{"nested and lists", "review:\n  fail-on: major\n  ignore:\n    - vendor/\nlang: ja\n", "ja"},
+		{"block scalar", "notes: |\n  line one\n  line two\nlang: de\n", "de"},
+		{"flow style", "{lang: fr, tags: [a, b]}\n", "fr"},
+		{"empty", "", ""},
+	}
+	for _, tt := range tests {
+		c, err := Parse([]byte(tt.content))
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if c.Lang != tt.want {
+			t.Errorf("%s: Lang = %q, want %q", tt.name, c.Lang, tt.want)
+		}
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	if _, err := Parse([]byte("lang: [en\n")); err == nil {
+		t.Error("expected error for invalid YAML")
+	}
+}
+
+func TestLoadRepo(t *testing.T) {
+	dir := t.TempDir()
+	c, err := LoadRepo(dir)
+	if err != nil || c.Lang != "" {
+		t.Fatalf("missing file: got %+v, %v", c, err)
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(dir, FileName), []byte("lang: en\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	c, err = LoadRepo(dir)
+	if err != nil || c.Lang != "en" {
+		t.Fatalf("got %+v, %v", c, err)
+	}
+}
@@ -0,0 +1,217 @@
+package conflict
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Hunk 是文件中的一处冲突
+type Hunk struct {
+	OursLabel   string
+	TheirsLabel string
+	Ours        string
+	// Base 仅在 diff3 风格的冲突标记中存在
+	Base      string
+	BaseLabel string
+	HasBase   bool
+	Theirs    string
+	// Before 与 After 为冲突前后的若干行上下文
+	Before string
+	After  string
+
+	// Resolution 为解决后的内容，Resolved 为 false 时保留冲突标记
+	Resolution string
+	Resolved   bool
+
+	// newline 为文件使用的换行符
+	newline string
+}
+
+// File 是解析后的冲突文件，由普通文本片段与冲突交替组成
+type File struct {
+	// Segments 中 Hunk 为 nil 的元素为普通文本
+	Segments []Segment
+
+	// newline 为文件使用的换行符，\r\n 或 \n
+	newline string
+}
+
+// Segment 是文件中的一段内容
+type Segment struct {
+	Text string
+	Hunk *Hunk
+}
+
+// Hunks 返回文件中的全部冲突
+func (f *File) Hunks() []*Hunk {
+	var hunks []*Hunk
+	for _, s := range f.Segments {
+		if s.Hunk != nil {
+			hunks = append(hunks, s.Hunk)
+		}
+	}
+	return hunks
+}
+
+// Parse 解析包含冲突标记的文件内容，contextLines 为每处冲突保留的上下文行数
+func Parse(content string, contextLines int) (*File, error) {
+	lines := strings.SplitAfter(content, "\n")
+	f := &File{newline: detectNewline(content)}
+
+	var text strings.Builder
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		if !isMarker(line, "<<<<<<<") {
+			text.WriteString(line)
+			continue
+		}
+
+		if text.Len() > 0 {
+			f.Segments = append(f.Segments, Segment{Text: text.String()})
+			text.Reset()
+		}
+
+		h := &Hunk{OursLabel: label(line, "<<<<<<<"), newline: f.newline}
+		var ours, base, theirs strings.Builder
+		current := &ours
+		closed := false
+		for i++; i < len(lines); i++ {
+			l := lines[i]
+			switch {
+			case isMarker(l, "|||||||"):
+				h.HasBase = true
+				h.BaseLabel = label(l, "|||||||")
+				current = &base
+			case strings.TrimRight(l, "\r\n") == "=======":
+				current = &theirs
+			case isMarker(l, ">>>>>>>"):
+				h.TheirsLabel = label(l, ">>>>>>>")
+				closed = true
+			default:
+				current.WriteString(l)
+			}
+			if closed {
+				break
+			}
+		}
+		if !closed {
+			return nil, fmt.Errorf("冲突标记不完整：缺少 >>>>>>>")
+		}
+
+		h.Ours, h.Base, h.Theirs = ours.String(), base.String(), theirs.String()
+		f.Segments = append(f.Segments, Segment{Hunk: h})
+	}
+	if text.Len() > 0 {
+		f.Segments = append(f.Segments, Segment{Text: text.String()})
+	}
+
+	for i, s := range f.Segments {
+		if s.Hunk == nil {
+			continue
+		}
+		if i > 0 && f.Segments[i-1].Hunk == nil {
+			s.Hunk.Before = lastLines(f.Segments[i-1].Text, contextLines)
+		}
+		if i+1 < len(f.Segments) && f.Segments[i+1].Hunk == nil {
+			s.Hunk.After = firstLines(f.Segments[i+1].Text, contextLines)
+		}
+	}
+	return f, nil
+}
+
+// String 重新生成文件内容，未解决的冲突保留原有的冲突标记
+func (f *File) String() string {
+	var b strings.Builder
+	for _, s := range f.Segments {
+		if s.Hunk == nil {
+			b.WriteString(s.Text)
+			continue
+		}
+		h := s.Hunk
+		if h.Resolved {
+			b.WriteString(h.Resolution)
+			continue
+		}
+		b.WriteString(marker("<<<<<<<", h.OursLabel, f.newline))
+		b.WriteString(h.Ours)
+		if h.HasBase {
+			b.WriteString(marker("|||||||", h.BaseLabel, f.newline))
+			b.WriteString(h.Base)
+		}
+		b.WriteString("=======" + f.newline)
+		b.WriteString(h.Theirs)
+		b.WriteString(marker(">>>>>>>", h.TheirsLabel, f.newline))
+	}
+	return b.String()
+}
+
+// Unresolved 返回尚未解决的冲突数量
+func (f *File) Unresolved() int {
+	n := 0
+	for _, h := range f.Hunks() {
+		if !h.Resolved {
+			n++
+		}
+	}
+	return n
+}
+
+// Resolve 使用给定内容解决冲突，并保证内容以换行结尾。
+// 文件使用 \r\n 换行时，内容中的换行符会统一转换为 \r\n。
+func (h *Hunk) Resolve(content string) {
+	if content != "" && !strings.HasSuffix(content, "\n") {
+		content += "\n"
+	}
+	if h.newline == "\r\n" {
+		content = strings.ReplaceAll(strings.ReplaceAll(content, "\r\n", "\n"), "\n", "\r\n")
+	}
+	h.Resolution = content
+	h.Resolved = true
+}
+
+// isMarker 判断一行是否为冲突标记：标记单独成行，或后面跟一个空格和标签。
+// Markdown、RST 中用作标题下划线的 ======= 或 >>>>>>>>> 不会被当作标记。
+func isMarker(line, prefix string) bool {
+	rest := strings.TrimRight(line, "\r\n")
+	if !strings.HasPrefix(rest, prefix) {
+		return false
+	}
+	rest = rest[len(prefix):]
+	return rest == "" || rest[0] == ' '
+}
+
+func label(line, prefix string) string {
+	return strings.TrimSpace(strings.TrimPrefix(line, prefix))
+}
+
+func marker(prefix, label, newline string) string {
+	if label == "" {
+		return prefix + newline
+	}
+	return prefix + " " + label + newline
+}
+
+// detectNewline 根据第一个换行判断文件使用的换行符
+func detectNewline(content string) string {
+	i := strings.Index(content, "\n")
+	if i > 0 && content[i-1] == '\r' {
+		return "\r\n"
+	}
+	return "\n"
+}
+
+func lastLines(s string, n int) string {
+	lines := strings.SplitAfter(strings.TrimSuffix(s, "\n"), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "") + "\n"
+}
+
+func firstLines(s string, n int) string {
+	lines := strings.SplitAfter(s, "\n")
+	if len(lines) > n {
+		lines = lines[:n]
+	}
+	return strings.Join(lines, "")
+}
@@ -0,0 +1,69 @@
+package conflict
+
+import "testing"
+
+func TestParseIgnoresHeadingUnderlines(t *testing.T) {
+	content := "Title\n=======\n\n<<<<<<< HEAD\nOurs\n========\n>>>>>>>>> not a marker\n=======\nTheirs\n>>>>>>> feature\nEnd\n"
+	f, err := Parse(content, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hunks := f.Hunks()
+	if len(hunks) != 1 {
+		t.Fatalf("got %d hunks, want 1", len(hunks))
+	}
+	h := hunks[0]
+	if want := "Ours\n========\n>>>>>>>>> not a marker\n"; h.Ours != want {
+		t.Errorf("Ours = %q, want %q", h.Ours, want)
+	}
+	if h.Theirs != "Theirs\n" || h.TheirsLabel != "feature" {
+		t.Errorf("Theirs = %q, label %q", h.Theirs, h.TheirsLabel)
+	}
+	if got := f.String(); got != content {
+		t.Errorf("String() = %q, want %q", got, content)
+	}
+}
+
+func TestParseDiff3(t *testing.T) {
+	content := "<<<<<<< ours\na\n||||||| base\nb\n=======\nc\n>>>>>>> theirs\n"
+	f, err := Parse(content, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := f.Hunks()[0]
+	if !h.HasBase || h.Ours != "a\n" || h.Base != "b\n" || h.Theirs != "c\n" {
+		t.Errorf("unexpected hunk: %+v", h)
+	}
+
+	h.Resolve("    indented\n\tcode")
+	if got, want := f.String(), "    indented\n\tcode\n"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
+
+func TestParseUnclosed(t *testing.T) {
+	if _, err := Parse("<<<<<<< HEAD\na\n=======\nb\n>>>>>>>>>\n", 3); err == nil {
+		t.Error("expected error for unclosed conflict")
+	}
+}
+
+func TestParseCRLF(t *testing.T) {
+	content := "a\r\n<<<<<<< HEAD\r\nours\r\n=======\r\ntheirs\r\n>>>>>>> feature\r\nb\r\n<<<<<<< HEAD\r\nx\r\n=======\r\ny\r\n>>>>>>> feature\r\n"
+	f, err := Parse(content, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hunks := f.Hunks()
+	if len(hunks) != 2 || hunks[0].OursLabel != "HEAD" || hunks[0].TheirsLabel != "feature" {
+		t.Fatalf("unexpected hunks: %+v", hunks)
+	}
+	if got := f.String(); got != content {
+		t.Errorf("String() = %q, want %q", got, content)
+	}
+
+	hunks[0].Resolve("merged\none\ntwo")
+	want := "a\r\nmerged\r\none\r\ntwo\r\nb\r\n<<<<<<< HEAD\r\nx\r\n=======\r\ny\r\n>>>>>>> feature\r\n"
+	if got := f.String(); got != want {
+		t.Errorf("String() after Resolve = %q, want %q", got, want)
+	}
+}
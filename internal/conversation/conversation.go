@@ -0,0 +1,127 @@
+package conversation
+
+import (
+	"fmt"
+	"strings"
+)
+
+// 消息角色，与聊天历史中的前缀保持一致
+const (
+	RoleSystem = "系统"
+	RoleUser   = "用户"
+	RoleAI     = "AI"
+)
+
+// Message 表示对话树中的一条消息
+type Message struct {
+	ID       int
+	Parent   int // 根节点为 -1
+	Role     string
+	Content  string
+	Children []int
+}
+
+// Tree 以树的形式保存对话，每个分叉代表一个可切换的后续分支
+type Tree struct {
+	Messages []*Message
+	Current  int // 当前分支末尾的消息 ID
+}
+
+// New 创建一棵以系统提示为根节点的对话树
+func New(systemPrompt string) *Tree {
+	t := &Tree{}
+	root := &Message{ID: 0, Parent: -1, Role: RoleSystem, Content: systemPrompt}
+	t.Messages = append(t.Messages, root)
+	t.Current = root.ID
+	return t
+}
+
+// Append 在当前消息之后追加一条消息，并将其设为当前消息
+func (t *Tree) Append(role, content string) *Message {
+	return t.addChild(t.Current, role, content)
+}
+
+func (t *Tree) addChild(parent int, role, content string) *Message {
+	msg := &Message{ID: len(t.Messages), Parent: parent, Role: role, Content: content}
+	t.Messages = append(t.Messages, msg)
+	t.Messages[parent].Children = append(t.Messages[parent].Children, msg.ID)
+	t.Current = msg.ID
+	return msg
+}
+
+// Path 返回从根节点到当前消息的路径
+func (t *Tree) Path() []*Message {
+	var path []*Message
+	for id := t.Current; id >= 0; id = t.Messages[id].Parent {
+		path = append([]*Message{t.Messages[id]}, path...)
+	}
+	return path
+}
+
+// History 返回当前分支的聊天历史，每行格式为 "角色: 内容"
+func (t *Tree) History() []string {
+	var history []string
+	for _, msg := range t.Path() {
+		history = append(history, fmt.Sprintf("%s: %s", msg.Role, msg.Content))
+	}
+	return history
+}
+
+// Prompt 将当前分支拼接为发送给 AI 的完整提示
+func (t *Tree) Prompt() string {
+	return strings.Join(t.History(), "\n") + "\n" + RoleAI + ":"
+}
+
+// UserMessages 返回当前分支上的用户消息，顺序与 /history 中的编号一致
+func (t *Tree) UserMessages() []*Message {
+	var msgs []*Message
+	for _, msg := range t.Path() {
+		if msg.Role == RoleUser {
+			msgs = append(msgs, msg)
+		}
+	}
+	return msgs
+}
+
+// Edit 改写当前分支上第 n 条（从 1 开始）用户消息。
+// 原消息及其后续保留在旧分支中，新消息作为其兄弟节点成为当前消息。
+func (t *Tree) Edit(n int, content string) (*Message, error) {
+	msgs := t.UserMessages()
+	if n < 1 || n > len(msgs) {
+		return nil, fmt.Errorf("消息编号 %d 超出范围 (1-%d)", n, len(msgs))
+	}
+	return t.addChild(msgs[n-1].Parent, RoleUser, content), nil
+}
+
+// Retry 回退到最后一条 AI 回复之前，以便重新生成时产生新的分支
+func (t *Tree) Retry() error {
+	cur := t.Messages[t.Current]
+	if cur.Role != RoleAI {
+		return fmt.Errorf("当前分支没有可重新生成的 AI 回复")
+	}
+	t.Current = cur.Parent
+	return nil
+}
+
+// Leaves 返回所有分支的末尾消息
+func (t *Tree) Leaves() []*Message {
+	var leaves []*Message
+	for _, msg := range t.Messages {
+		if len(msg.Children) == 0 && msg.Parent >= 0 {
+			leaves = append(leaves, msg)
+		}
+	}
+	return leaves
+}
+
+// Switch 切换到以指定消息结尾的分支，id 必须是 Leaves 返回的分支末尾消息
+func (t *Tree) Switch(id int) error {
+	if id < 0 || id >= len(t.Messages) {
+		return fmt.Errorf("不存在 ID 为 %d 的消息", id)
+	}
+	if msg := t.Messages[id]; len(msg.Children) > 0 || msg.Parent < 0 {
+		return fmt.Errorf("消息 %d 不是分支末尾，可通过 /branches 查看可切换的分支", id)
+	}
+	t.Current = id
+	return nil
+}
@@ -0,0 +1,128 @@
+package conversation
+
+import (
+	"reflect"
+	"testing"
+)
+
+// newChat 创建一段包含两轮问答的对话：
+// 0 系统 → 1 用户 → 2 AI → 3 用户 → 4 AI
+func newChat() *Tree {
+	t := New("你是助手")
+	t.Append(RoleUser, "你好")
+	t.Append(RoleAI, "你好！")
+	t.Append(RoleUser, "1+1=?")
+	t.Append(RoleAI, "2")
+	return t
+}
+
+func pathIDs(t *Tree) []int {
+	var ids []int
+	for _, msg := range t.Path() {
+		ids = append(ids, msg.ID)
+	}
+	return ids
+}
+
+func leafIDs(t *Tree) []int {
+	var ids []int
+	for _, msg := range t.Leaves() {
+		ids = append(ids, msg.ID)
+	}
+	return ids
+}
+
+func TestHistoryAndPrompt(t *testing.T) {
+	tree := newChat()
+	want := []string{"系统: 你是助手", "用户: 你好", "AI: 你好！", "用户: 1+1=?", "AI: 2"}
+	if got := tree.History(); !reflect.DeepEqual(got, want) {
+		t.Errorf("History() = %q, want %q", got, want)
+	}
+	if got, want := tree.Prompt(), "系统: 你是助手\n用户: 你好\nAI: 你好！\n用户: 1+1=?\nAI: 2\nAI:"; got != want {
+		t.Errorf("Prompt() = %q, want %q", got, want)
+	}
+	if got := len(tree.UserMessages()); got != 2 {
+		t.Errorf("UserMessages() returned %d messages, want 2", got)
+	}
+	if got := leafIDs(tree); !reflect.DeepEqual(got, []int{4}) {
+		t.Errorf("Leaves() = %v, want [4]", got)
+	}
+}
+
+func TestEdit(t *testing.T) {
+	tree := newChat()
+	msg, err := tree.Edit(1, "  早上好\n  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.ID != 5 || msg.Parent != 0 || msg.Content != "  早上好\n  " || tree.Current != 5 {
+		t.Errorf("Edit() = %+v, Current = %d", msg, tree.Current)
+	}
+	if got := pathIDs(tree); !reflect.DeepEqual(got, []int{0, 5}) {
+		t.Errorf("path after Edit = %v, want [0 5]", got)
+	}
+	if got := tree.Messages[0].Children; !reflect.DeepEqual(got, []int{1, 5}) {
+		t.Errorf("root children = %v, want [1 5]", got)
+	}
+
+	tree.Append(RoleAI, "早！")
+	if got := leafIDs(tree); !reflect.DeepEqual(got, []int{4, 6}) {
+		t.Errorf("Leaves() = %v, want [4 6]", got)
+	}
+
+	// 编号按当前分支计算，新分支上只有一条用户消息
+	for _, n := range []int{0, 2} {
+		if _, err := tree.Edit(n, "x"); err == nil {
+			t.Errorf("Edit(%d) should fail on a branch with one user message", n)
+		}
+	}
+}
+
+func TestRetry(t *testing.T) {
+	tree := newChat()
+	if err := tree.Retry(); err != nil {
+		t.Fatal(err)
+	}
+	if tree.Current != 3 {
+		t.Errorf("Current after Retry = %d, want 3", tree.Current)
+	}
+	if err := tree.Retry(); err == nil {
+		t.Error("Retry() without a trailing AI reply should fail")
+	}
+
+	tree.Append(RoleAI, "二")
+	if got := pathIDs(tree); !reflect.DeepEqual(got, []int{0, 1, 2, 3, 5}) {
+		t.Errorf("path after regenerating = %v", got)
+	}
+	if got := leafIDs(tree); !reflect.DeepEqual(got, []int{4, 5}) {
+		t.Errorf("Leaves() = %v, want [4 5]", got)
+	}
+}
+
+func TestSwitch(t *testing.T) {
+	tree := newChat()
+	tree.Retry()
+	tree.Append(RoleAI, "二")
+
+	if err := tree.Switch(4); err != nil {
+		t.Fatal(err)
+	}
+	if got := tree.History(); got[len(got)-1] != "AI: 2" {
+		t.Errorf("History() after Switch = %q", got)
+	}
+
+	for _, id := range []int{-1, 6, 100} {
+		if err := tree.Switch(id); err == nil {
+			t.Errorf("Switch(%d) should fail for a missing message", id)
+		}
+	}
+	// 根节点和中间节点不是分支末尾
+	for _, id := range []int{0, 1, 3} {
+		if err := tree.Switch(id); err == nil {
+			t.Errorf("Switch(%d) should fail for a message that is not a leaf", id)
+		}
+	}
+	if tree.Current != 4 {
+		t.Errorf("failed Switch changed Current to %d", tree.Current)
+	}
+}
@@ -0,0 +1,52 @@
+package githelper
+
+import (
+	"strings"
+)
+
+// CommitOptions 控制提交方式
+type CommitOptions struct {
+	// Author 覆盖提交作者，格式为 "Name <email>"
+	Author string
+	// SignOff 为 true 时添加 Signed-off-by 脚注
+	SignOff bool
+	// GPGSign 为 true 时使用 GPG 签名提交，GPGKey 为空时使用默认密钥
+	GPGSign bool
+	GPGKey  string
+	// Amend 为 true 时修订上一次提交
+	Amend bool
+	// AllowEmpty 为 true 时允许没有变更的提交
+	AllowEmpty bool
+}
+
+// Commit 使用暂存区内容创建提交，返回新提交的哈希
+func (r *Repo) Commit(message string, opts CommitOptions) (string, error) {
+	args := []string{"commit", "--quiet", "--file=-"}
+	if opts.Author != "" {
+		args = append(args, "--author="+opts.Author)
+	}
+	if opts.SignOff {
+		args = append(args, "--signoff")
+	}
+	if opts.GPGSign {
+		args = append(args, "--gpg-sign"+keySuffix(opts.GPGKey))
+	}
+	if opts.Amend {
+		args = append(args, "--amend")
+	}
+	if opts.AllowEmpty {
+		args = append(args, "--allow-empty")
+	}
+
+	if _, err := r.outputWithInput(strings.NewReader(message), args...); err != nil {
+		return "", err
+	}
+	return r.RevParse("HEAD")
+}
+
+func keySuffix(key string) string {
+	if key == "" {
+		return ""
+	}
+	return "=" + key
+}
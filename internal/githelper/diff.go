@@ -0,0 +1,276 @@
+package githelper
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DiffOptions 控制差异的收集与截断方式
+type DiffOptions struct {
+	// Staged 为 true 时收集暂存区差异，否则收集工作区差异
+	Staged bool
+	// Base 非空时与指定提交比较，例如修订上一次提交时使用 HEAD~1；
+	// 形如 "main...HEAD" 的范围则比较两个提交
+	Base string
+	// Paths 非空时只收集指定路径的差异
+	Paths []string
+	// MaxFileLines 单个文件保留的最大差异行数，<=0 表示不限制
+	MaxFileLines int
+	// TokenBudget 整体差异的 token 预算，超出部分仅保留摘要，<=0 表示不限制
+	TokenBudget int
+}
+
+// FileDiff 描述单个文件的差异
+type FileDiff struct {
+	Path      string
+	Status    string
+	Added     int
+	Deleted   int
+	Binary    bool
+	Patch     string
+	Truncated bool
+	// Excluded 非空时表示该文件的差异内容被省略，值为省略原因
+	Excluded string
+}
+
+// lockFiles 是常见的依赖锁文件，其差异对生成描述没有帮助
+var lockFiles = map[string]bool{
+	"go.sum":            true,
+	"package-lock.json": true,
+	"yarn.lock":         true,
+	"pnpm-lock.yaml":    true,
+	"Cargo.lock":        true,
+	"poetry.lock":       true,
+	"Pipfile.lock":      true,
+	"Gemfile.lock":      true,
+	"composer.lock":     true,
+}
+
+// vendorDirs 是常见的第三方代码目录
+var vendorDirs = []string{"vendor/", "node_modules/", "third_party/"}
+
+// StagedDiff 收集暂存区相对 HEAD 的差异
+func (r *Repo) StagedDiff(opts DiffOptions) ([]FileDiff, error) {
+	opts.Staged = true
+	return r.Diff(opts)
+}
+
+// UnstagedDiff 收集工作区相对暂存区的差异
+func (r *Repo) UnstagedDiff(opts DiffOptions) ([]FileDiff, error) {
+	opts.Staged = false
+	return r.Diff(opts)
+}
+
+// Diff 收集差异并按文件拆分，二进制文件、锁文件和第三方代码只保留统计信息
+func (r *Repo) Diff(opts DiffOptions) ([]FileDiff, error) {
+	args := []string{"diff", "--no-color", "--no-ext-diff", "--no-renames"}
+	if opts.Staged {
+		args = append(args, "--staged")
+	}
+	if opts.Base != "" {
+		args = append(args, opts.Base)
+	}
+	withPaths := func(extra ...string) []string {
+		a := append(append([]string(nil), args...), extra...)
+		if len(opts.Paths) > 0 {
+			a = append(append(a, "--"), opts.Paths...)
+		}
+		return a
+	}
+
+	statusOut, err := r.output(withPaths("--name-status")...)
+	if err != nil {
+		return nil, err
+	}
+	numstatOut, err := r.output(withPaths("--numstat")...)
+	if err != nil {
+		return nil, err
+	}
+	patchOut, err := r.output(withPaths()...)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []FileDiff
+	index := map[string]int{}
+	for _, line := range splitLines(statusOut) {
+		parts := strings.SplitN(line, "\t", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		index[parts[1]] = len(files)
+		files = append(files, FileDiff{Path: parts[1], Status: parts[0]})
+	}
+
+	for _, line := range splitLines(numstatOut) {
+		parts := strings.SplitN(line, "\t", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		i, ok := index[parts[2]]
+		if !ok {
+			continue
+		}
+		if parts[0] == "-" && parts[1] == "-" {
+			files[i].Binary = true
+			continue
+		}
+		files[i].Added, _ = strconv.Atoi(parts[0])
+		files[i].Deleted, _ = strconv.Atoi(parts[1])
+	}
+
+	for p, patch := range splitPatch(patchOut) {
+		if i, ok := index[p]; ok {
+			files[i].Patch = patch
+		}
+	}
+
+	for i := range files {
+		f := &files[i]
+		if reason := excludeReason(f); reason != "" {
+			f.Excluded = reason
+			f.Patch = ""
+			continue
+		}
+		if opts.MaxFileLines > 0 {
+			f.Patch, f.Truncated = truncateLines(f.Patch, opts.MaxFileLines)
+		}
+	}
+
+	if opts.TokenBudget > 0 {
+		applyTokenBudget(files, opts.TokenBudget)
+	}
+
+	return files, nil
+}
+
+// FormatDiff 将差异格式化为适合放入提示词的文本
+func FormatDiff(files []FileDiff) string {
+	if len(files) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("变更文件:\n")
+	for _, f := range files {
+		b.WriteString(fmt.Sprintf("%s\t%s\t%s\n", f.Status, f.Path, f.Stat()))
+	}
+
+	var omitted []FileDiff
+	b.WriteString("\n代码差异:\n")
+	for _, f := range files {
+		if f.Excluded != "" {
+			omitted = append(omitted, f)
+			continue
+		}
+		b.WriteString(f.Patch)
+		if !strings.HasSuffix(f.Patch, "\n") {
+			b.WriteString("\n")
+		}
+		if f.Truncated {
+			b.WriteString(fmt.Sprintf("... (%s 的差异过长，已截断)\n", f.Path))
+		}
+	}
+
+	if len(omitted) > 0 {
+		b.WriteString("\n以下文件仅保留摘要:\n")
+		for _, f := range omitted {
+			b.WriteString(fmt.Sprintf("- %s %s: %s\n", f.Path, f.Stat(), f.Excluded))
+		}
+	}
+
+	return strings.TrimSpace(b.String())
+}
+
+// Stat 返回文件的增删行统计
+func (f FileDiff) Stat() string {
+	if f.Binary {
+		return "(二进制)"
+	}
+	return fmt.Sprintf("(+%d -%d)", f.Added, f.Deleted)
+}
+
+// EstimateTokens 粗略估算文本的 token 数
+func EstimateTokens(s string) int {
+	return (len(s) + 3) / 4
+}
+
+func excludeReason(f *FileDiff) string {
+	if f.Binary {
+		return "二进制文件"
+	}
+	if lockFiles[path.Base(f.Path)] {
+		return "依赖锁文件"
+	}
+	for _, dir := range vendorDirs {
+		if strings.HasPrefix(f.Path, dir) || strings.Contains(f.Path, "/"+dir) {
+			return "第三方代码"
+		}
+	}
+	if strings.HasSuffix(f.Path, ".min.js") || strings.HasSuffix(f.Path, ".min.css") {
+		return "压缩文件"
+	}
+	return ""
+}
+
+// applyTokenBudget 优先保留较小文件的完整差异，超出预算的文件只保留摘要
+func applyTokenBudget(files []FileDiff, budget int) {
+	order := make([]int, 0, len(files))
+	for i := range files {
+		if files[i].Excluded == "" {
+			order = append(order, i)
+		}
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return len(files[order[a]].Patch) < len(files[order[b]].Patch)
+	})
+
+	used := 0
+	for _, i := range order {
+		tokens := EstimateTokens(files[i].Patch)
+		if used+tokens <= budget {
+			used += tokens
+			continue
+		}
+		files[i].Excluded = "差异超出长度预算"
+		files[i].Patch = ""
+	}
+}
+
+// splitPatch 将完整的 diff 输出按文件拆分
+func splitPatch(out string) map[string]string {
+	patches := map[string]string{}
+	var current string
+	var b strings.Builder
+	flush := func() {
+		if current != "" {
+			patches[current] = b.String()
+		}
+		b.Reset()
+	}
+
+	for _, line := range strings.SplitAfter(out, "\n") {
+		if strings.HasPrefix(line, "diff --git ") {
+			flush()
+			current = ""
+			header := strings.TrimSpace(line)
+			if i := strings.LastIndex(header, " b/"); i >= 0 {
+				current = header[i+3:]
+			}
+		}
+		b.WriteString(line)
+	}
+	flush()
+	return patches
+}
+
+func truncateLines(s string, max int) (string, bool) {
+	lines := strings.SplitAfter(s, "\n")
+	if len(lines) <= max {
+		return s, false
+	}
+	return strings.Join(lines[:max], ""), true
+}
@@ -1,34 +1,183 @@
 package githelper
 
 import (
+	"bytes"
+	"fmt"
+	"io"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 )
 
-// GetGitChanges 获取当前 Git 仓库的变更信息
-func GetGitChanges() (string, error) {
-	cmd := exec.Command("git", "status", "--short")
-	output, err := cmd.Output()
+// Repo 表示一个 Git 仓库，所有操作都在仓库根目录下执行
+type Repo struct {
+	Dir string
+}
+
+// Open 打开 dir 所在的 Git 仓库，dir 为空时使用当前目录
+func Open(dir string) (*Repo, error) {
+	if dir == "" {
+		dir = "."
+	}
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Repo{Dir: abs}
+	top, err := r.output("rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, fmt.Errorf("%s 不是 Git 仓库: %v", abs, err)
+	}
+	r.Dir = strings.TrimSpace(top)
+	return r, nil
+}
+
+// Init 在 dir 中初始化一个新仓库，主要用于临时仓库
+func Init(dir string) (*Repo, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	r := &Repo{Dir: dir}
+	if err := r.run("init", "--quiet"); err != nil {
+		return nil, err
+	}
+	return Open(dir)
+}
+
+// CurrentBranch 返回当前分支名，处于分离 HEAD 状态时返回 HEAD
+func (r *Repo) CurrentBranch() (string, error) {
+	out, err := r.output("rev-parse", "--abbrev-ref", "HEAD")
 	if err != nil {
 		return "", err
 	}
-	return strings.TrimSpace(string(output)), nil
+	return strings.TrimSpace(out), nil
 }
 
-// GitCommit 执行 git commit 命令
-func GitCommit(message string) error {
-	// 确保所有变更被添加
-	addCmd := exec.Command("git", "add", ".")
-	addCmd.Stdout = os.Stdout
-	addCmd.Stderr = os.Stderr
-	if err := addCmd.Run(); err != nil {
-		return err
+// BranchExists 判断本地分支是否存在
+func (r *Repo) BranchExists(name string) bool {
+	return r.refExists("refs/heads/" + name)
+}
+
+func (r *Repo) refExists(ref string) bool {
+	_, err := r.output("rev-parse", "--verify", "--quiet", ref)
+	return err == nil
+}
+
+// CheckBranchName 使用 git check-ref-format --branch 检查分支名是否合法
+func (r *Repo) CheckBranchName(name string) error {
+	if _, err := r.output("check-ref-format", "--branch", name); err != nil {
+		return fmt.Errorf("分支名 %q 不合法", name)
+	}
+	return nil
+}
+
+// CreateBranch 基于当前 HEAD 创建分支，checkout 为 true 时同时切换到新分支
+func (r *Repo) CreateBranch(name string, checkout bool) error {
+	if checkout {
+		return r.run("checkout", "-b", name)
+	}
+	return r.run("branch", name)
+}
+
+// DefaultBranch 推断用于比较的主分支。优先返回远程分支：origin/HEAD 指向的分支，其次为 origin/main 或
+// origin/master，这样本地主分支不存在或落后于远程时也能得到正确的差异；没有远程分支时使用本地的 main 或 master。
+func (r *Repo) DefaultBranch() (string, error) {
+	if out, err := r.output("symbolic-ref", "--quiet", "--short", "refs/remotes/origin/HEAD"); err == nil {
+		return strings.TrimSpace(out), nil
+	}
+	for _, name := range []string{"main", "master"} {
+		if r.refExists("refs/remotes/origin/" + name) {
+			return "origin/" + name, nil
+		}
+	}
+	for _, name := range []string{"main", "master"} {
+		if r.BranchExists(name) {
+			return name, nil
+		}
 	}
+	return "", fmt.Errorf("无法确定主分支，请手动指定")
+}
 
-	// 执行 commit
-	commitCmd := exec.Command("git2", "commit", "-m", message)
-	commitCmd.Stdout = os.Stdout
-	commitCmd.Stderr = os.Stderr
-	return commitCmd.Run()
+// LatestTag 返回从 HEAD 可达的最近一个标签
+func (r *Repo) LatestTag() (string, error) {
+	out, err := r.output("describe", "--tags", "--abbrev=0")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// RevParse 将引用解析为完整的提交哈希
+func (r *Repo) RevParse(ref string) (string, error) {
+	out, err := r.output("rev-parse", "--verify", ref)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// GitPath 返回 .git 目录下指定路径的实际位置，会遵循 core.hooksPath 等配置
+func (r *Repo) GitPath(name string) (string, error) {
+	out, err := r.output("rev-parse", "--git-path", name)
+	if err != nil {
+		return "", err
+	}
+	p := strings.TrimSpace(out)
+	if !filepath.IsAbs(p) {
+		p = filepath.Join(r.Dir, p)
+	}
+	return p, nil
+}
+
+// InRebase 判断当前仓库是否处于 rebase 过程中
+func (r *Repo) InRebase() bool {
+	for _, name := range []string{"rebase-merge", "rebase-apply"} {
+		p, err := r.GitPath(name)
+		if err != nil {
+			continue
+		}
+		if _, err := os.Stat(p); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// command 构造在仓库目录下执行的 git 命令，路径输出不做转义
+func (r *Repo) command(args ...string) *exec.Cmd {
+	cmd := exec.Command("git", append([]string{"-c", "core.quotePath=false"}, args...)...)
+	cmd.Dir = r.Dir
+	return cmd
+}
+
+// output 执行 git 命令并返回标准输出，失败时错误中包含标准错误输出
+func (r *Repo) output(args ...string) (string, error) {
+	return r.outputWithInput(nil, args...)
+}
+
+func (r *Repo) outputWithInput(stdin io.Reader, args ...string) (string, error) {
+	cmd := r.command(args...)
+	cmd.Stdin = stdin
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("执行 'git %s' 失败: %v\n输出: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return string(out), nil
+}
+
+func (r *Repo) run(args ...string) error {
+	_, err := r.output(args...)
+	return err
+}
+
+func splitLines(s string) []string {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\n")
 }
@@ -0,0 +1,306 @@
+package githelper
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// newTestRepo 在临时目录中创建一个主分支为 main 的空仓库
+func newTestRepo(t *testing.T) *Repo {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git 未安装")
+	}
+	// 避免读取用户的全局配置（签名、hooks 等）
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+
+	r, err := Init(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, args := range [][]string{
+		{"symbolic-ref", "HEAD", "refs/heads/main"},
+		{"config", "user.name", "Test"},
+		{"config", "user.email", "test@example.com"},
+	} {
+		if err := r.run(args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return r
+}
+
+func writeFile(t *testing.T, r *Repo, name, content string) {
+	t.Helper()
+	p := filepath.Join(r.Dir, name)
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(p, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// commitFile 写入文件并提交，返回提交哈希
+func commitFile(t *testing.T, r *Repo, name, content, message string) string {
+	t.Helper()
+	writeFile(t, r, name, content)
+	if err := r.StagePaths([]string{name}); err != nil {
+		t.Fatal(err)
+	}
+	hash, err := r.Commit(message, CommitOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hash
+}
+
+func TestOpen(t *testing.T) {
+	r := newTestRepo(t)
+	writeFile(t, r, "sub/dir/a.txt", "a\n")
+
+	opened, err := Open(filepath.Join(r.Dir, "sub", "dir"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, _ := filepath.EvalSymlinks(r.Dir)
+	got, _ := filepath.EvalSymlinks(opened.Dir)
+	if got != want {
+		t.Errorf("Open(subdir).Dir = %q, want %q", got, want)
+	}
+
+	if _, err := Open(t.TempDir()); err == nil {
+		t.Error("Open on a non-repository directory should fail")
+	}
+}
+
+func TestStatusAndStaging(t *testing.T) {
+	r := newTestRepo(t)
+	commitFile(t, r, "a.txt", "one\n", "[feat] add a")
+
+	writeFile(t, r, "a.txt", "one\ntwo\n")
+	writeFile(t, r, "b.txt", "new\n")
+
+	unstaged, err := r.UnstagedFiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []FileStatus{{Status: "M", Path: "a.txt"}, {Status: "??", Path: "b.txt"}}
+	if !reflect.DeepEqual(unstaged, want) {
+		t.Errorf("UnstagedFiles() = %+v, want %+v", unstaged, want)
+	}
+
+	if err := r.StageTracked(); err != nil {
+		t.Fatal(err)
+	}
+	staged, err := r.StagedFiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []FileStatus{{Status: "M", Path: "a.txt"}}; !reflect.DeepEqual(staged, want) {
+		t.Errorf("StagedFiles() after StageTracked = %+v, want %+v", staged, want)
+	}
+
+	if err := r.StagePaths([]string{"b.txt"}); err != nil {
+		t.Fatal(err)
+	}
+	staged, _ = r.StagedFiles()
+	if want := []FileStatus{{Status: "M", Path: "a.txt"}, {Status: "A", Path: "b.txt"}}; !reflect.DeepEqual(staged, want) {
+		t.Errorf("StagedFiles() after StagePaths = %+v, want %+v", staged, want)
+	}
+
+	if err := r.UnstageAll(); err != nil {
+		t.Fatal(err)
+	}
+	staged, _ = r.StagedFiles()
+	if len(staged) != 0 {
+		t.Errorf("StagedFiles() after UnstageAll = %+v, want none", staged)
+	}
+}
+
+func TestPartiallyStagedFiles(t *testing.T) {
+	r := newTestRepo(t)
+	commitFile(t, r, "a.txt", "1\n2\n3\n", "[feat] add a")
+	commitFile(t, r, "b.txt", "b\n", "[feat] add b")
+
+	writeFile(t, r, "a.txt", "one\n2\n3\n")
+	writeFile(t, r, "b.txt", "bb\n")
+	if err := r.StagePaths([]string{"a.txt", "b.txt"}); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, r, "a.txt", "one\n2\nthree\n")
+
+	partial, err := r.PartiallyStagedFiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"a.txt"}; !reflect.DeepEqual(partial, want) {
+		t.Errorf("PartiallyStagedFiles() = %v, want %v", partial, want)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	r := newTestRepo(t)
+	first := commitFile(t, r, "a.txt", "1\n2\n3\n", "[feat] add a")
+	commitFile(t, r, "go.sum", "x v1\n", "[feat] add go.sum")
+
+	writeFile(t, r, "a.txt", "1\ntwo\n3\n4\n")
+	writeFile(t, r, "go.sum", "x v2\n")
+	if err := r.StageTracked(); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := r.StagedDiff(DiffOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("StagedDiff() returned %d files, want 2", len(files))
+	}
+	a := files[0]
+	if a.Path != "a.txt" || a.Added != 2 || a.Deleted != 1 {
+		t.Errorf("a.txt diff = %+v, want 2 added and 1 deleted", a)
+	}
+	if !strings.Contains(a.Patch, "+two") || !strings.Contains(a.Patch, "-2") {
+		t.Errorf("a.txt patch missing changes:\n%s", a.Patch)
+	}
+	if files[1].Path != "go.sum" || files[1].Excluded == "" {
+		t.Errorf("go.sum should be excluded as a lock file: %+v", files[1])
+	}
+
+	unstaged, err := r.UnstagedDiff(DiffOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(unstaged) != 0 {
+		t.Errorf("UnstagedDiff() = %+v, want none", unstaged)
+	}
+
+	if _, err := r.Commit("[fix] update a", CommitOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	ranged, err := r.Diff(DiffOptions{Base: first + "...HEAD", Paths: []string{"a.txt"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ranged) != 1 || ranged[0].Path != "a.txt" {
+		t.Errorf("Diff(range, paths) = %+v, want only a.txt", ranged)
+	}
+}
+
+func TestCommitAndLog(t *testing.T) {
+	r := newTestRepo(t)
+	commitFile(t, r, "a.txt", "a\n", "[feat] add a")
+
+	writeFile(t, r, "b.txt", "b\n")
+	if err := r.StagePaths([]string{"b.txt"}); err != nil {
+		t.Fatal(err)
+	}
+	hash, err := r.Commit("[feat] add b\n\nbody line", CommitOptions{SignOff: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if head, _ := r.RevParse("HEAD"); head != hash {
+		t.Errorf("Commit() = %s, HEAD = %s", hash, head)
+	}
+
+	commits, err := r.Log(LogOptions{MaxCount: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := commits[0]
+	if c.Hash != hash || c.Subject != "[feat] add b" || c.Author != "Test" || c.Email != "test@example.com" {
+		t.Errorf("Log()[0] = %+v", c)
+	}
+	if !strings.HasPrefix(c.Body, "body line") || !strings.Contains(c.Body, "Signed-off-by: Test <test@example.com>") {
+		t.Errorf("Log()[0].Body = %q", c.Body)
+	}
+
+	amended, err := r.Commit("[feat] add b and amend", CommitOptions{Amend: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if amended == hash {
+		t.Error("amend should create a new commit")
+	}
+	commits, _ = r.Log(LogOptions{})
+	if len(commits) != 2 || commits[0].Subject != "[feat] add b and amend" {
+		t.Errorf("Log() after amend = %+v", commits)
+	}
+
+	if _, err := r.Commit("[fix] nothing", CommitOptions{}); err == nil {
+		t.Error("Commit() without changes should fail")
+	}
+	if _, err := r.Commit("[fix] nothing", CommitOptions{AllowEmpty: true}); err != nil {
+		t.Errorf("Commit() with AllowEmpty: %v", err)
+	}
+}
+
+func TestBranches(t *testing.T) {
+	r := newTestRepo(t)
+	commitFile(t, r, "a.txt", "a\n", "[feat] add a")
+
+	if branch, err := r.CurrentBranch(); err != nil || branch != "main" {
+		t.Errorf("CurrentBranch() = %q, %v", branch, err)
+	}
+	if err := r.CreateBranch("feat/x", true); err != nil {
+		t.Fatal(err)
+	}
+	if branch, _ := r.CurrentBranch(); branch != "feat/x" {
+		t.Errorf("CurrentBranch() after CreateBranch = %q", branch)
+	}
+	if !r.BranchExists("feat/x") || r.BranchExists("feat/y") {
+		t.Error("BranchExists() returned unexpected results")
+	}
+}
+
+func TestDefaultBranch(t *testing.T) {
+	upstream := newTestRepo(t)
+	commitFile(t, upstream, "a.txt", "a\n", "[feat] add a")
+
+	if base, err := upstream.DefaultBranch(); err != nil || base != "main" {
+		t.Errorf("DefaultBranch() without remote = %q, %v, want main", base, err)
+	}
+
+	// 只克隆了功能分支、本地没有 main 时仍然使用 origin/main
+	dir := filepath.Join(t.TempDir(), "clone")
+	if out, err := exec.Command("git", "clone", "--quiet", upstream.Dir, dir).CombinedOutput(); err != nil {
+		t.Fatalf("git clone: %v\n%s", err, out)
+	}
+	clone, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := clone.CreateBranch("feature", true); err != nil {
+		t.Fatal(err)
+	}
+	if err := clone.run("branch", "-D", "main"); err != nil {
+		t.Fatal(err)
+	}
+	if base, err := clone.DefaultBranch(); err != nil || base != "origin/main" {
+		t.Errorf("DefaultBranch() in clone = %q, %v, want origin/main", base, err)
+	}
+
+	// 没有 origin/HEAD 时按 origin/main 查找
+	if err := clone.run("remote", "set-head", "origin", "--delete"); err != nil {
+		t.Fatal(err)
+	}
+	if base, err := clone.DefaultBranch(); err != nil || base != "origin/main" {
+		t.Errorf("DefaultBranch() without origin/HEAD = %q, %v, want origin/main", base, err)
+	}
+
+	other := newTestRepo(t)
+	if err := other.run("symbolic-ref", "HEAD", "refs/heads/trunk"); err != nil {
+		t.Fatal(err)
+	}
+	commitFile(t, other, "a.txt", "a\n", "[feat] add a")
+	if base, err := other.DefaultBranch(); err == nil {
+		t.Errorf("DefaultBranch() without main or master = %q, want error", base)
+	}
+}
@@ -0,0 +1,154 @@
+package githelper
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Commit 描述一次提交
+type Commit struct {
+	Hash    string
+	Author  string
+	Email   string
+	Date    time.Time
+	Subject string
+	Body    string
+}
+
+// ShortHash 返回提交哈希的前 7 位
+func (c Commit) ShortHash() string {
+	if len(c.Hash) > 7 {
+		return c.Hash[:7]
+	}
+	return c.Hash
+}
+
+// LogOptions 控制提交记录的查询范围
+type LogOptions struct {
+	// Range 为提交范围，例如 "v1.2.0..HEAD"，为空时从 HEAD 开始
+	Range string
+	// MaxCount 为最多返回的提交数，<=0 表示不限制
+	MaxCount int
+	// NoMerges 为 true 时忽略合并提交
+	NoMerges bool
+	// Paths 非空时只返回涉及指定路径的提交
+	Paths []string
+}
+
+const (
+	logFieldSep  = "\x1f"
+	logRecordSep = "\x1e"
+)
+
+// Log 返回提交记录，按时间从新到旧排列
+func (r *Repo) Log(opts LogOptions) ([]Commit, error) {
+	args := []string{"log", "--format=" + strings.Join([]string{"%H", "%an", "%ae", "%aI", "%s", "%b"}, logFieldSep) + logRecordSep}
+	if opts.MaxCount > 0 {
+		args = append(args, fmt.Sprintf("--max-count=%d", opts.MaxCount))
+	}
+	if opts.NoMerges {
+		args = append(args, "--no-merges")
+	}
+	if opts.Range != "" {
+		args = append(args, opts.Range)
+	}
+	if len(opts.Paths) > 0 {
+		args = append(append(args, "--"), opts.Paths...)
+	}
+
+	out, err := r.output(args...)
+	if err != nil {
+		return nil, err
+	}
+
+	var commits []Commit
+	for _, record := range strings.Split(out, logRecordSep) {
+		fields := strings.Split(strings.TrimLeft(record, "\n"), logFieldSep)
+		if len(fields) != 6 {
+			continue
+		}
+		date, _ := time.Parse(time.RFC3339, fields[3])
+		commits = append(commits, Commit{
+			Hash:    fields[0],
+			Author:  fields[1],
+			Email:   fields[2],
+			Date:    date,
+			Subject: fields[4],
+			Body:    strings.TrimSpace(fields[5]),
+		})
+	}
+	return commits, nil
+}
+
+// BlameLine 描述 blame 结果中的一行
+type BlameLine struct {
+	Line    int
+	Hash    string
+	Author  string
+	Date    time.Time
+	Summary string
+	Content string
+}
+
+// Blame 返回文件指定行范围的 blame 信息，start 和 end 为 0 时返回整个文件
+func (r *Repo) Blame(path string, start, end int) ([]BlameLine, error) {
+	args := []string{"blame", "--porcelain"}
+	if start > 0 && end >= start {
+		args = append(args, fmt.Sprintf("-L%d,%d", start, end))
+	}
+	args = append(args, "--", path)
+
+	out, err := r.output(args...)
+	if err != nil {
+		return nil, err
+	}
+
+	// porcelain 格式中每个提交的详细信息只在第一次出现时输出
+	type commitInfo struct {
+		author  string
+		date    time.Time
+		summary string
+	}
+	infos := map[string]*commitInfo{}
+
+	var lines []BlameLine
+	var current *BlameLine
+	for _, line := range strings.Split(out, "\n") {
+		if current == nil {
+			fields := strings.Fields(line)
+			if len(fields) < 3 {
+				continue
+			}
+			n, err := strconv.Atoi(fields[2])
+			if err != nil {
+				continue
+			}
+			current = &BlameLine{Hash: fields[0], Line: n}
+			if infos[current.Hash] == nil {
+				infos[current.Hash] = &commitInfo{}
+			}
+			continue
+		}
+
+		info := infos[current.Hash]
+		switch {
+		case strings.HasPrefix(line, "\t"):
+			current.Content = line[1:]
+			current.Author = info.author
+			current.Date = info.date
+			current.Summary = info.summary
+			lines = append(lines, *current)
+			current = nil
+		case strings.HasPrefix(line, "author "):
+			info.author = strings.TrimPrefix(line, "author ")
+		case strings.HasPrefix(line, "author-time "):
+			sec, _ := strconv.ParseInt(strings.TrimPrefix(line, "author-time "), 10, 64)
+			info.date = time.Unix(sec, 0)
+		case strings.HasPrefix(line, "summary "):
+			info.summary = strings.TrimPrefix(line, "summary ")
+		}
+	}
+	return lines, nil
+}
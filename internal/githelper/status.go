@@ -0,0 +1,117 @@
+package githelper
+
+import (
+	"strconv"
+	"strings"
+)
+
+// FileStatus 描述工作区中一个文件的状态
+type FileStatus struct {
+	Status string
+	Path   string
+}
+
+// StagedFiles 返回暂存区中的文件
+func (r *Repo) StagedFiles() ([]FileStatus, error) {
+	return r.nameStatus("diff", "--staged", "--name-status", "--no-renames")
+}
+
+// UnstagedFiles 返回尚未暂存的已跟踪文件变更以及未跟踪文件
+func (r *Repo) UnstagedFiles() ([]FileStatus, error) {
+	out, err := r.output("status", "--porcelain", "--untracked-files=all")
+	if err != nil {
+		return nil, err
+	}
+
+	var files []FileStatus
+	for _, line := range strings.Split(out, "\n") {
+		if len(line) < 4 {
+			continue
+		}
+		code := line[:2]
+		if code != "??" && code[1] == ' ' {
+			continue
+		}
+		p := line[3:]
+		if i := strings.Index(p, " -> "); i >= 0 {
+			p = p[i+4:]
+		}
+		if unquoted, err := strconv.Unquote(p); err == nil {
+			p = unquoted
+		}
+		files = append(files, FileStatus{Status: strings.TrimSpace(code), Path: p})
+	}
+	return files, nil
+}
+
+// PartiallyStagedFiles 返回同时存在已暂存和未暂存修改的文件，通常是用 git add -p 只暂存了部分修改
+func (r *Repo) PartiallyStagedFiles() ([]string, error) {
+	out, err := r.output("status", "--porcelain", "--untracked-files=no")
+	if err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	for _, line := range strings.Split(out, "\n") {
+		if len(line) < 4 || line[0] == ' ' || line[1] == ' ' {
+			continue
+		}
+		p := line[3:]
+		if i := strings.Index(p, " -> "); i >= 0 {
+			p = p[i+4:]
+		}
+		if unquoted, err := strconv.Unquote(p); err == nil {
+			p = unquoted
+		}
+		paths = append(paths, p)
+	}
+	return paths, nil
+}
+
+// StageTracked 暂存所有已跟踪文件的变更，不包含未跟踪文件
+func (r *Repo) StageTracked() error {
+	return r.run("add", "--update")
+}
+
+// StagePaths 暂存指定的文件
+func (r *Repo) StagePaths(paths []string) error {
+	if len(paths) == 0 {
+		return nil
+	}
+	return r.run(append([]string{"add", "--"}, paths...)...)
+}
+
+func (r *Repo) nameStatus(args ...string) ([]FileStatus, error) {
+	out, err := r.output(args...)
+	if err != nil {
+		return nil, err
+	}
+	var files []FileStatus
+	for _, line := range splitLines(out) {
+		parts := strings.SplitN(line, "\t", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		files = append(files, FileStatus{Status: parts[0], Path: parts[1]})
+	}
+	return files, nil
+}
+
+// UnstageAll 清空暂存区，工作区内容保持不变
+func (r *Repo) UnstageAll() error {
+	return r.run("reset", "--quiet")
+}
+
+// ConflictedFiles 返回存在未解决冲突的文件
+func (r *Repo) ConflictedFiles() ([]string, error) {
+	out, err := r.output("diff", "--name-only", "--diff-filter=U")
+	if err != nil {
+		return nil, err
+	}
+	return splitLines(out), nil
+}
+
+// RestoreConflictDiff3 以 diff3 风格重新生成文件的冲突标记，文件中已做的修改会被覆盖
+func (r *Repo) RestoreConflictDiff3(path string) error {
+	return r.run("checkout", "--conflict=diff3", "--", path)
+}
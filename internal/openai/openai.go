@@ -2,6 +2,7 @@ package openai
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"github.com/sirupsen/logrus"
@@ -48,6 +49,11 @@ func getOpenAIConfig() (string, string, string, error) {
 }
 
 func GenerateContent(prompt string) (string, error) {
+	return GenerateContentContext(context.Background(), prompt)
+}
+
+// GenerateContentContext 与 GenerateContent 相同，ctx 取消或超时时中止请求
+func GenerateContentContext(ctx context.Context, prompt string) (string, error) {
 	apiURL, apiKey, model, err := getOpenAIConfig()
 	if err != nil {
 		return "", err
@@ -68,7 +74,7 @@ func GenerateContent(prompt string) (string, error) {
 		return "", err
 	}
 
-	req, err := http.NewRequest("POST", apiURL, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", apiURL, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return "", err
 	}
@@ -1,23 +1,34 @@
 package provider
 
 import (
+	"context"
 	"github.com/fanook/aicli/internal/deepseek"
 	"github.com/fanook/aicli/internal/openai"
 	"github.com/sirupsen/logrus"
 	"os"
 )
 
-func GenerateContent(prompt string) (string, error) {
-	provider := os.Getenv("AICLI_PROVIDER")
-	if provider == "" {
-		provider = "openai"
+// Name 返回当前使用的 AI 提供商，由 AICLI_PROVIDER 指定，默认为 openai
+func Name() string {
+	if provider := os.Getenv("AICLI_PROVIDER"); provider != "" {
+		return provider
 	}
+	return "openai"
+}
+
+func GenerateContent(prompt string) (string, error) {
+	return GenerateContentContext(context.Background(), prompt)
+}
+
+// GenerateContentContext 与 GenerateContent 相同，ctx 取消或超时时中止请求
+func GenerateContentContext(ctx context.Context, prompt string) (string, error) {
+	provider := Name()
 
 	switch provider {
 	case "openai":
-		return openai.GenerateContent(prompt)
+		return openai.GenerateContentContext(ctx, prompt)
 	case "deepseek":
-		return deepseek.GenerateContent(prompt)
+		return deepseek.GenerateContentContext(ctx, prompt)
 	default:
 		logrus.Fatalf("未支持的 AI 提供商: %s, 请检查配置。", provider)
 		return "", nil
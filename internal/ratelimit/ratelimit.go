@@ -0,0 +1,156 @@
+// Package ratelimit 按每分钟请求数和每分钟 token 数限制对 AI 提供商的调用
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// window 是限流统计的时间窗口
+const window = time.Minute
+
+type event struct {
+	at     time.Time
+	tokens int
+}
+
+// Limiter 在一分钟的滑动窗口内同时限制请求数和 token 数，限制值为 0 表示不限制。
+// 可以被多个 goroutine 同时使用。
+type Limiter struct {
+	rpm, tpm int
+
+	mu       sync.Mutex
+	requests []time.Time
+	tokens   []event
+}
+
+// New 创建限流器
+func New(rpm, tpm int) *Limiter {
+	return &Limiter{rpm: rpm, tpm: tpm}
+}
+
+// ForProvider 读取 AICLI_<PROVIDER>_RPM 和 AICLI_<PROVIDER>_TPM 环境变量创建限流器，
+// rpm、tpm 大于 0 时覆盖环境变量中的值
+func ForProvider(provider string, rpm, tpm int) (*Limiter, error) {
+	prefix := "AICLI_" + strings.ToUpper(provider) + "_"
+	var err error
+	if rpm <= 0 {
+		if rpm, err = envInt(prefix + "RPM"); err != nil {
+			return nil, err
+		}
+	}
+	if tpm <= 0 {
+		if tpm, err = envInt(prefix + "TPM"); err != nil {
+			return nil, err
+		}
+	}
+	return New(rpm, tpm), nil
+}
+
+func envInt(name string) (int, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("环境变量 %s 必须为非负整数: %q", name, value)
+	}
+	return n, nil
+}
+
+// String 返回限流配置的描述
+func (l *Limiter) String() string {
+	describe := func(n int) string {
+		if n <= 0 {
+			return "不限"
+		}
+		return strconv.Itoa(n)
+	}
+	return fmt.Sprintf("每分钟请求数 %s，每分钟 token 数 %s", describe(l.rpm), describe(l.tpm))
+}
+
+// Wait 阻塞直到可以发起一个消耗 tokens 个 token 的请求，并将其计入窗口。
+// 单个请求的 token 数超过每分钟限制时，等窗口清空后放行，避免永远阻塞。
+func (l *Limiter) Wait(ctx context.Context, tokens int) error {
+	for {
+		l.mu.Lock()
+		now := time.Now()
+		l.expire(now)
+		delay := l.delay(now, tokens)
+		if delay == 0 {
+			l.requests = append(l.requests, now)
+			l.tokens = append(l.tokens, event{at: now, tokens: tokens})
+			l.mu.Unlock()
+			return nil
+		}
+		l.mu.Unlock()
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// Add 在窗口中追加 token 消耗，用于请求完成后计入回复的 token 数
+func (l *Limiter) Add(tokens int) {
+	if tokens <= 0 {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.tokens = append(l.tokens, event{at: time.Now(), tokens: tokens})
+}
+
+func (l *Limiter) expire(now time.Time) {
+	i := 0
+	for i < len(l.requests) && now.Sub(l.requests[i]) >= window {
+		i++
+	}
+	l.requests = l.requests[i:]
+
+	i = 0
+	for i < len(l.tokens) && now.Sub(l.tokens[i].at) >= window {
+		i++
+	}
+	l.tokens = l.tokens[i:]
+}
+
+// delay 返回还需等待多久才能发起请求，0 表示可以立即发起
+func (l *Limiter) delay(now time.Time, tokens int) time.Duration {
+	var wait time.Duration
+	if l.rpm > 0 && len(l.requests) >= l.rpm {
+		wait = l.requests[len(l.requests)-l.rpm].Add(window).Sub(now)
+	}
+
+	if l.tpm > 0 {
+		used := 0
+		for _, e := range l.tokens {
+			used += e.tokens
+		}
+		// 从最早的记录开始过期，直到腾出足够的 token；窗口清空后总是放行
+		for _, e := range l.tokens {
+			if used+tokens <= l.tpm {
+				break
+			}
+			used -= e.tokens
+			if d := e.at.Add(window).Sub(now); d > wait {
+				wait = d
+			}
+		}
+	}
+
+	if wait < 0 {
+		wait = 0
+	}
+	return wait
+}
@@ -0,0 +1,125 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+var base = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func at(seconds int) time.Time {
+	return base.Add(time.Duration(seconds) * time.Second)
+}
+
+func TestDelayUnlimited(t *testing.T) {
+	l := New(0, 0)
+	for i := 0; i < 100; i++ {
+		l.requests = append(l.requests, at(0))
+		l.tokens = append(l.tokens, event{at: at(0), tokens: 1000})
+	}
+	if d := l.delay(at(1), 1000); d != 0 {
+		t.Errorf("delay() = %v, want 0", d)
+	}
+}
+
+func TestDelayRPM(t *testing.T) {
+	l := New(2, 0)
+	if d := l.delay(at(0), 0); d != 0 {
+		t.Fatalf("empty window: delay() = %v, want 0", d)
+	}
+
+	l.requests = []time.Time{at(0), at(10)}
+	// 窗口已满，需要等最早的请求在第 60 秒过期
+	if d := l.delay(at(20), 0); d != 40*time.Second {
+		t.Errorf("full window: delay() = %v, want 40s", d)
+	}
+
+	l.requests = []time.Time{at(10)}
+	if d := l.delay(at(20), 0); d != 0 {
+		t.Errorf("window with room: delay() = %v, want 0", d)
+	}
+}
+
+func TestDelayTPM(t *testing.T) {
+	l := New(0, 100)
+	l.tokens = []event{{at: at(0), tokens: 40}, {at: at(10), tokens: 40}}
+
+	if d := l.delay(at(20), 20); d != 0 {
+		t.Errorf("within budget: delay() = %v, want 0", d)
+	}
+	// 需要释放第一条记录的 40 个 token
+	if d := l.delay(at(20), 30); d != 40*time.Second {
+		t.Errorf("over budget: delay() = %v, want 40s", d)
+	}
+	// 需要释放两条记录
+	if d := l.delay(at(20), 90); d != 50*time.Second {
+		t.Errorf("needs both events expired: delay() = %v, want 50s", d)
+	}
+}
+
+func TestDelayOversizeRequest(t *testing.T) {
+	l := New(0, 100)
+	// 窗口为空时，超过每分钟限制的单个请求也会放行
+	if d := l.delay(at(0), 500); d != 0 {
+		t.Errorf("empty window: delay() = %v, want 0", d)
+	}
+	l.tokens = []event{{at: at(0), tokens: 10}}
+	if d := l.delay(at(30), 500); d != 30*time.Second {
+		t.Errorf("non-empty window: delay() = %v, want 30s", d)
+	}
+}
+
+func TestDelayTakesLongerLimit(t *testing.T) {
+	l := New(1, 100)
+	l.requests = []time.Time{at(30)}
+	l.tokens = []event{{at: at(0), tokens: 90}, {at: at(30), tokens: 5}}
+	// RPM 需要等到第 90 秒，TPM 只需等到第 60 秒
+	if d := l.delay(at(40), 20); d != 50*time.Second {
+		t.Errorf("delay() = %v, want 50s", d)
+	}
+}
+
+func TestExpire(t *testing.T) {
+	l := New(10, 100)
+	l.requests = []time.Time{at(0), at(30)}
+	l.tokens = []event{{at: at(0), tokens: 10}, {at: at(30), tokens: 20}}
+	l.expire(at(60))
+	if len(l.requests) != 1 || !l.requests[0].Equal(at(30)) {
+		t.Errorf("requests after expire = %v", l.requests)
+	}
+	if len(l.tokens) != 1 || l.tokens[0].tokens != 20 {
+		t.Errorf("tokens after expire = %v", l.tokens)
+	}
+}
+
+func TestWaitCanceled(t *testing.T) {
+	l := New(1, 0)
+	if err := l.Wait(context.Background(), 0); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := l.Wait(ctx, 0); err != context.Canceled {
+		t.Errorf("Wait() = %v, want context.Canceled", err)
+	}
+}
+
+func TestForProvider(t *testing.T) {
+	t.Setenv("AICLI_DEEPSEEK_RPM", "30")
+	t.Setenv("AICLI_DEEPSEEK_TPM", "5000")
+
+	l, err := ForProvider("deepseek", 0, 0)
+	if err != nil || l.rpm != 30 || l.tpm != 5000 {
+		t.Errorf("ForProvider() = %+v, %v", l, err)
+	}
+	l, err = ForProvider("deepseek", 10, 0)
+	if err != nil || l.rpm != 10 || l.tpm != 5000 {
+		t.Errorf("ForProvider() with flag = %+v, %v", l, err)
+	}
+
+	t.Setenv("AICLI_DEEPSEEK_RPM", "-1")
+	if _, err := ForProvider("deepseek", 0, 0); err == nil {
+		t.Error("ForProvider() with negative env should fail")
+	}
+}
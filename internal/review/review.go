@@ -0,0 +1,161 @@
+package review
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Severity 是问题的严重程度
+type Severity string
+
+const (
+	SeverityInfo     Severity = "info"
+	SeverityMinor    Severity = "minor"
+	SeverityMajor    Severity = "major"
+	SeverityCritical Severity = "critical"
+)
+
+// Severities 按严重程度从低到高排列
+var Severities = []Severity{SeverityInfo, SeverityMinor, SeverityMajor, SeverityCritical}
+
+// Rank 返回严重程度的排序值，未知的严重程度视为 info
+func (s Severity) Rank() int {
+	for i, sev := range Severities {
+		if sev == s {
+			return i
+		}
+	}
+	return 0
+}
+
+// ParseSeverity 解析严重程度，"none" 或空字符串返回 false
+func ParseSeverity(s string) (Severity, bool, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" || s == "none" {
+		return "", false, nil
+	}
+	for _, sev := range Severities {
+		if string(sev) == s {
+			return sev, true, nil
+		}
+	}
+	return "", false, fmt.Errorf("未知的严重程度 %q，可选值为 none、info、minor、major、critical", s)
+}
+
+// Finding 是一条代码审查意见
+type Finding struct {
+	File       string   `json:"file"`
+	Line       int      `json:"line"`
+	Severity   Severity `json:"severity"`
+	Message    string   `json:"message"`
+	Suggestion string   `json:"suggestion,omitempty"`
+}
+
+// severityAliases 是 AI 回复中常见的其他严重程度写法
+var severityAliases = map[string]Severity{
+	"note":       SeverityInfo,
+	"nit":        SeverityInfo,
+	"suggestion": SeverityInfo,
+	"low":        SeverityMinor,
+	"warning":    SeverityMinor,
+	"medium":     SeverityMajor,
+	"high":       SeverityMajor,
+	"error":      SeverityMajor,
+	"blocker":    SeverityCritical,
+}
+
+// normalizeSeverity 将 AI 给出的严重程度转换为标准值，无法识别时返回错误，
+// 避免未知的严重程度被当作 info 而绕过 --fail-on 检查
+func normalizeSeverity(s Severity) (Severity, error) {
+	name := strings.ToLower(strings.TrimSpace(string(s)))
+	if sev, ok := severityAliases[name]; ok {
+		return sev, nil
+	}
+	sev, ok, err := ParseSeverity(name)
+	if err != nil || !ok {
+		return "", fmt.Errorf("未知的严重程度 %q", s)
+	}
+	return sev, nil
+}
+
+var hunkHeader = regexp.MustCompile(`^@@ -\d+(?:,\d+)? \+(\d+)(?:,\d+)? @@`)
+
+// AnnotatePatch 在差异的新增行和上下文行前标注新文件中的行号，便于 AI 给出准确的位置
+func AnnotatePatch(patch string) string {
+	var b strings.Builder
+	line := 0
+	for _, l := range strings.Split(strings.TrimRight(patch, "\n"), "\n") {
+		if m := hunkHeader.FindStringSubmatch(l); m != nil {
+			line, _ = strconv.Atoi(m[1])
+			b.WriteString(l + "\n")
+			continue
+		}
+		if line > 0 && (strings.HasPrefix(l, "+") || strings.HasPrefix(l, " ")) {
+			b.WriteString(fmt.Sprintf("%5d %s\n", line, l))
+			line++
+			continue
+		}
+		b.WriteString("      " + l + "\n")
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// ParseFindings 从 AI 回复中解析 JSON 数组形式的审查意见
+func ParseFindings(response, file string) ([]Finding, error) {
+	start := strings.Index(response, "[")
+	end := strings.LastIndex(response, "]")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("回复中没有找到 JSON 数组: %s", response)
+	}
+
+	var findings []Finding
+	if err := json.Unmarshal([]byte(response[start:end+1]), &findings); err != nil {
+		return nil, fmt.Errorf("解析审查结果失败: %v", err)
+	}
+	for i := range findings {
+		sev, err := normalizeSeverity(findings[i].Severity)
+		if err != nil {
+			return nil, fmt.Errorf("解析审查结果失败: 第 %d 条意见: %v", i+1, err)
+		}
+		findings[i].File = file
+		findings[i].Severity = sev
+	}
+	return findings, nil
+}
+
+// Exceeds 判断是否存在严重程度不低于阈值的意见
+func Exceeds(findings []Finding, threshold Severity) bool {
+	for _, f := range findings {
+		if f.Severity.Rank() >= threshold.Rank() {
+			return true
+		}
+	}
+	return false
+}
+
+// FormatText 将审查意见格式化为终端文本
+func FormatText(findings []Finding) string {
+	if len(findings) == 0 {
+		return "未发现问题。"
+	}
+	var b strings.Builder
+	for _, f := range findings {
+		b.WriteString(fmt.Sprintf("%s:%d [%s] %s\n", f.File, f.Line, f.Severity, f.Message))
+		if f.Suggestion != "" {
+			b.WriteString("    建议: " + strings.ReplaceAll(f.Suggestion, "\n", "\n    ") + "\n")
+		}
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// FormatJSON 将审查意见格式化为 JSON
+func FormatJSON(findings []Finding) (string, error) {
+	if findings == nil {
+		findings = []Finding{}
+	}
+	data, err := json.MarshalIndent(findings, "", "  ")
+	return string(data), err
+}
@@ -0,0 +1,192 @@
+package review
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseFindings(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		want     []Finding
+		wantErr  bool
+	}{
+		{
+			name:     "plain array",
+			response: `[{"line": 3, "severity": "major", "message": "nil check"}]`,
+			want:     []Finding{{File: "a.go", Line: 3, Severity: SeverityMajor, Message: "nil check"}},
+		},
+		{
+			name:     "surrounded by prose and fences",
+			response: "结果如下：\n```json\n[{\"line\": 1, \"severity\": \"Critical\", \"message\": \"sql\", \"suggestion\": \"use args\"}]\n```\n",
+			want:     []Finding{{File: "a.go", Line: 1, Severity: SeverityCritical, Message: "sql", Suggestion: "use args"}},
+		},
+		{
+			name:     "empty array",
+			response: "[]",
+			want:     []Finding{},
+		},
+		{
+			name:     "synonyms",
+			response: `[{"severity": "high"}, {"severity": "warning"}, {"severity": "blocker"}, {"severity": "nit"}, {"severity": "error"}]`,
+			want: []Finding{
+				{File: "a.go", Severity: SeverityMajor},
+				{File: "a.go", Severity: SeverityMinor},
+				{File: "a.go", Severity: SeverityCritical},
+				{File: "a.go", Severity: SeverityInfo},
+				{File: "a.go", Severity: SeverityMajor},
+			},
+		},
+		{name: "unknown severity", response: `[{"severity": "catastrophic"}]`, wantErr: true},
+		{name: "missing severity", response: `[{"message": "x"}]`, wantErr: true},
+		{name: "none is not a finding severity", response: `[{"severity": "none"}]`, wantErr: true},
+		{name: "no array", response: "没有发现问题", wantErr: true},
+		{name: "invalid json", response: `[{"severity": }]`, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseFindings(tt.response, "a.go")
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: ParseFindings() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: ParseFindings() = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestExceeds(t *testing.T) {
+	findings := []Finding{{Severity: SeverityInfo}, {Severity: SeverityMajor}}
+	tests := []struct {
+		findings  []Finding
+		threshold Severity
+		want      bool
+	}{
+		{findings, SeverityInfo, true},
+		{findings, SeverityMajor, true},
+		{findings, SeverityCritical, false},
+		{nil, SeverityInfo, false},
+	}
+	for _, tt := range tests {
+		if got := Exceeds(tt.findings, tt.threshold); got != tt.want {
+			t.Errorf("Exceeds(%v, %s) = %v, want %v", tt.findings, tt.threshold, got, tt.want)
+		}
+	}
+}
+
+func TestAnnotatePatch(t *testing.T) {
+	tests := []struct {
+		name  string
+		patch string
+		want  string
+	}{
+		{
+			name:  "added and context lines",
+			patch: "@@ -1,2 +10,3 @@ func f()\n a\n-b\n+c\n+d\n",
+			want:  "@@ -1,2 +10,3 @@ func f()\n   10  a\n      -b\n   11 +c\n   12 +d",
+		},
+		{
+			name:  "multiple hunks",
+			patch: "@@ -1 +1 @@\n-x\n+y\n@@ -20,0 +21 @@\n+z\n",
+			want:  "@@ -1 +1 @@\n      -x\n    1 +y\n@@ -20,0 +21 @@\n   21 +z",
+		},
+		{
+			name:  "lines before the first hunk",
+			patch: "+not numbered\n@@ -1 +5 @@\n+y\n",
+			want:  "      +not numbered\n@@ -1 +5 @@\n    5 +y",
+		},
+	}
+	for _, tt := range tests {
+		if got := AnnotatePatch(tt.patch); got != tt.want {
+			t.Errorf("%s: AnnotatePatch() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestFormatSARIF(t *testing.T) {
+	tests := []struct {
+		finding Finding
+		level   string
+		region  bool
+	}{
+		{Finding{File: "a.go", Line: 3, Severity: SeverityCritical, Message: "m", Suggestion: "s"}, "error", true},
+		{Finding{File: "a.go", Line: 4, Severity: SeverityMajor, Message: "m"}, "error", true},
+		{Finding{File: "b.go", Line: 1, Severity: SeverityMinor, Message: "m"}, "warning", true},
+		{Finding{File: "b.go", Severity: SeverityInfo, Message: "m"}, "note", false},
+	}
+	var findings []Finding
+	for _, tt := range tests {
+		findings = append(findings, tt.finding)
+	}
+
+	out, err := FormatSARIF(findings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var log struct {
+		Schema  string `json:"$schema"`
+		Version string `json:"version"`
+		Runs    []struct {
+			Tool struct {
+				Driver struct {
+					Name string `json:"name"`
+				} `json:"driver"`
+			} `json:"tool"`
+			Results []struct {
+				RuleID  string `json:"ruleId"`
+				Level   string `json:"level"`
+				Message struct {
+					Text string `json:"text"`
+				} `json:"message"`
+				Locations []struct {
+					PhysicalLocation struct {
+						ArtifactLocation struct {
+							URI string `json:"uri"`
+						} `json:"artifactLocation"`
+						Region *struct {
+							StartLine int `json:"startLine"`
+						} `json:"region"`
+					} `json:"physicalLocation"`
+				} `json:"locations"`
+			} `json:"results"`
+		} `json:"runs"`
+	}
+	if err := json.Unmarshal([]byte(out), &log); err != nil {
+		t.Fatalf("FormatSARIF() is not valid JSON: %v", err)
+	}
+	if log.Version != "2.1.0" || log.Schema == "" || len(log.Runs) != 1 || log.Runs[0].Tool.Driver.Name == "" {
+		t.Fatalf("unexpected SARIF envelope:\n%s", out)
+	}
+	results := log.Runs[0].Results
+	if len(results) != len(tests) {
+		t.Fatalf("got %d results, want %d", len(results), len(tests))
+	}
+	for i, tt := range tests {
+		r := results[i]
+		if r.Level != tt.level || r.RuleID != "aicli-review/"+string(tt.finding.Severity) || r.Message.Text == "" {
+			t.Errorf("result %d = %+v", i, r)
+		}
+		if len(r.Locations) != 1 || r.Locations[0].PhysicalLocation.ArtifactLocation.URI != tt.finding.File {
+			t.Errorf("result %d locations = %+v", i, r.Locations)
+			continue
+		}
+		region := r.Locations[0].PhysicalLocation.Region
+		if (region != nil) != tt.region || (region != nil && region.StartLine != tt.finding.Line) {
+			t.Errorf("result %d region = %+v, want line %d", i, region, tt.finding.Line)
+		}
+	}
+	if results[0].Message.Text != "m\n建议: s" {
+		t.Errorf("suggestion not included in message: %q", results[0].Message.Text)
+	}
+
+	empty, err := FormatSARIF(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(empty, `"results": []`) {
+		t.Errorf("FormatSARIF(nil) should contain an empty results array:\n%s", empty)
+	}
+}
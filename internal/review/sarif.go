@@ -0,0 +1,94 @@
+package review
+
+import "encoding/json"
+
+// SARIF 2.1.0 中用到的最小结构
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string `json:"name"`
+	InformationURI string `json:"informationUri"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+}
+
+// FormatSARIF 将审查意见格式化为 SARIF 2.1.0，便于导入代码扫描平台
+func FormatSARIF(findings []Finding) (string, error) {
+	results := []sarifResult{}
+	for _, f := range findings {
+		text := f.Message
+		if f.Suggestion != "" {
+			text += "\n建议: " + f.Suggestion
+		}
+		loc := sarifPhysicalLocation{ArtifactLocation: sarifArtifactLocation{URI: f.File}}
+		if f.Line > 0 {
+			loc.Region = &sarifRegion{StartLine: f.Line}
+		}
+		results = append(results, sarifResult{
+			RuleID:    "aicli-review/" + string(f.Severity),
+			Level:     sarifLevel(f.Severity),
+			Message:   sarifMessage{Text: text},
+			Locations: []sarifLocation{{PhysicalLocation: loc}},
+		})
+	}
+
+	log := sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs: []sarifRun{{
+			Tool:    sarifTool{Driver: sarifDriver{Name: "aicli review", InformationURI: "https://github.com/fanook/aicli"}},
+			Results: results,
+		}},
+	}
+	data, err := json.MarshalIndent(log, "", "  ")
+	return string(data), err
+}
+
+func sarifLevel(s Severity) string {
+	switch s {
+	case SeverityCritical, SeverityMajor:
+		return "error"
+	case SeverityMinor:
+		return "warning"
+	}
+	return "note"
+}
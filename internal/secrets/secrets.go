@@ -0,0 +1,345 @@
+package secrets
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Rule 是一条敏感信息检测规则
+type Rule struct {
+	Name    string `json:"name"`
+	Pattern string `json:"pattern"`
+	// Group 指定需要屏蔽的子匹配组，0 表示屏蔽整个匹配
+	Group int `json:"group"`
+
+	re *regexp.Regexp
+}
+
+// Config 是扫描器的配置，可通过 AICLI_SECRETS_CONFIG 指定 JSON 配置文件
+type Config struct {
+	// Rules 为额外的检测规则
+	Rules []Rule `json:"rules"`
+	// Disable 为需要禁用的内置规则名称
+	Disable []string `json:"disable"`
+	// Allow 中的正则匹配到的内容不会被视为敏感信息，例如示例占位符
+	Allow []string `json:"allow"`
+	// EntropyThreshold 为高熵字符串的判定阈值，为 0 时使用默认值，设为负数可关闭高熵检测
+	EntropyThreshold float64 `json:"entropy_threshold"`
+}
+
+// Finding 描述一处疑似敏感信息
+type Finding struct {
+	Rule   string
+	File   string
+	Line   int
+	Masked string
+}
+
+// DefaultEntropyThreshold 是默认的高熵判定阈值（每字符比特数）
+const DefaultEntropyThreshold = 4.2
+
+// defaultRules 是内置的检测规则
+var defaultRules = []Rule{
+	{Name: "aws-access-key", Pattern: `\b(AKIA|ASIA)[0-9A-Z]{16}\b`},
+	{Name: "github-token", Pattern: `\bgh[pousr]_[A-Za-z0-9]{36,}\b`},
+	{Name: "openai-key", Pattern: `\bsk-[A-Za-z0-9_-]{20,}`},
+	{Name: "slack-token", Pattern: `\bxox[abprs]-[A-Za-z0-9-]{10,}`},
+	{Name: "google-api-key", Pattern: `\bAIza[0-9A-Za-z_-]{35}\b`},
+	{Name: "jwt", Pattern: `\beyJ[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}`},
+	{Name: "url-credentials", Pattern: `[a-zA-Z][a-zA-Z0-9+.-]*://[^/\s:@]+:([^/\s:@]+)@`, Group: 1},
+	{Name: "password", Pattern: `(?i)(?:password|passwd|pwd|secret|api[_-]?key|access[_-]?token|auth[_-]?token)["']?\s*[:=]\s*["']?([^\s"'{}$]{6,})`, Group: 1},
+}
+
+var privateKeyBegin = regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY( BLOCK)?-----`)
+var privateKeyEnd = regexp.MustCompile(`-----END [A-Z ]*PRIVATE KEY( BLOCK)?-----`)
+var hunkHeader = regexp.MustCompile(`^@@ -\d+(?:,\d+)? \+(\d+)(?:,\d+)? @@`)
+var tokenPattern = regexp.MustCompile(`[A-Za-z0-9+/_=-]{20,}`)
+var envAssignment = regexp.MustCompile(`^(\s*(?:export\s+)?[A-Za-z_][A-Za-z0-9_]*\s*=\s*)(.+)$`)
+
+// Scanner 在文本中检测并屏蔽敏感信息
+type Scanner struct {
+	rules            []Rule
+	allow            []*regexp.Regexp
+	entropyThreshold float64
+}
+
+// NewScanner 根据配置创建扫描器
+func NewScanner(cfg Config) (*Scanner, error) {
+	disabled := map[string]bool{}
+	for _, name := range cfg.Disable {
+		disabled[name] = true
+	}
+
+	s := &Scanner{entropyThreshold: cfg.EntropyThreshold}
+	if s.entropyThreshold == 0 {
+		s.entropyThreshold = DefaultEntropyThreshold
+	}
+
+	for _, r := range append(append([]Rule(nil), defaultRules...), cfg.Rules...) {
+		if disabled[r.Name] {
+			continue
+		}
+		re, err := regexp.Compile(r.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("规则 %s 的正则无效: %v", r.Name, err)
+		}
+		r.re = re
+		s.rules = append(s.rules, r)
+	}
+
+	for _, pattern := range cfg.Allow {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("白名单正则 %q 无效: %v", pattern, err)
+		}
+		s.allow = append(s.allow, re)
+	}
+	return s, nil
+}
+
+// LoadScanner 读取 AICLI_SECRETS_CONFIG 指定的配置并创建扫描器，未配置时使用内置规则。
+// 路径开头的 ~/ 会展开为用户主目录。
+func LoadScanner() (*Scanner, error) {
+	var cfg Config
+	if p := os.Getenv("AICLI_SECRETS_CONFIG"); p != "" {
+		p, err := expandHome(p)
+		if err != nil {
+			return nil, fmt.Errorf("读取敏感信息规则配置失败: %v", err)
+		}
+		data, err := ioutil.ReadFile(p)
+		if err != nil {
+			return nil, fmt.Errorf("读取敏感信息规则配置失败: %v", err)
+		}
+		if err := json.Unmarshal(data, &cfg); err != nil {
+			return nil, fmt.Errorf("解析敏感信息规则配置失败: %v", err)
+		}
+	}
+	return NewScanner(cfg)
+}
+
+// expandHome 将路径开头的 ~/ 展开为用户主目录
+func expandHome(p string) (string, error) {
+	if p != "~" && !strings.HasPrefix(p, "~/") {
+		return p, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, p[1:]), nil
+}
+
+// Scan 检测文本中的敏感信息，返回屏蔽后的文本与检测结果。
+// 对于 git diff 格式的文本，检测结果会标注所在文件及新文件中的行号。
+func (s *Scanner) Scan(text string) (string, []Finding) {
+	var findings []Finding
+	lines := strings.Split(text, "\n")
+
+	file := ""
+	lineNo := 0
+	// inHeader 表示位于 diff --git 与第一个 @@ 之间的文件头
+	inHeader := false
+	inPrivateKey := false
+	isDiff := strings.HasPrefix(text, "diff --git ") || strings.Contains(text, "\ndiff --git ")
+
+	for i, line := range lines {
+		if isDiff {
+			switch {
+			case strings.HasPrefix(line, "diff --git "):
+				if j := strings.LastIndex(line, " b/"); j >= 0 {
+					file = line[j+3:]
+				}
+				lineNo = 0
+				inHeader = true
+				continue
+			case inHeader && (strings.HasPrefix(line, "index ") || strings.HasPrefix(line, "--- ") || strings.HasPrefix(line, "+++ ")):
+				continue
+			}
+			if m := hunkHeader.FindStringSubmatch(line); m != nil {
+				n, _ := strconv.Atoi(m[1])
+				lineNo = n - 1
+				inHeader = false
+				continue
+			}
+			if !strings.HasPrefix(line, "-") {
+				lineNo++
+			}
+		} else {
+			lineNo = i + 1
+		}
+
+		report := func(rule, masked string) {
+			findings = append(findings, Finding{Rule: rule, File: file, Line: lineNo, Masked: masked})
+		}
+
+		if inPrivateKey || privateKeyBegin.MatchString(line) {
+			if !inPrivateKey {
+				report("private-key", strings.TrimSpace(line[len(diffPrefix(line, isDiff)):]))
+			}
+			inPrivateKey = !privateKeyEnd.MatchString(line)
+			if !privateKeyBegin.MatchString(line) && !privateKeyEnd.MatchString(line) {
+				lines[i] = diffPrefix(line, isDiff) + mask("private-key")
+			}
+			continue
+		}
+
+		if isDiff && isEnvFile(file) {
+			body := strings.TrimPrefix(strings.TrimPrefix(line, "+"), "-")
+			if m := envAssignment.FindStringSubmatch(body); m != nil {
+				if s.allowed(m[2]) {
+					continue
+				}
+				lines[i] = diffPrefix(line, isDiff) + m[1] + mask("env-file")
+				report("env-file", m[1]+mask("env-file"))
+				continue
+			}
+		}
+
+		masked := line
+		for _, r := range s.rules {
+			masked = s.replaceRule(r, masked, report)
+		}
+
+		if s.entropyThreshold > 0 {
+			masked = tokenPattern.ReplaceAllStringFunc(masked, func(tok string) string {
+				if s.allowed(tok) || !looksRandom(tok) || entropy(tok) < s.entropyThreshold {
+					return tok
+				}
+				report("high-entropy", preview(tok))
+				return mask("high-entropy")
+			})
+		}
+		lines[i] = masked
+	}
+
+	return strings.Join(lines, "\n"), findings
+}
+
+func (s *Scanner) replaceRule(r Rule, line string, report func(rule, masked string)) string {
+	matches := r.re.FindAllStringSubmatchIndex(line, -1)
+	if matches == nil {
+		return line
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, m := range matches {
+		start, end := m[0], m[1]
+		if r.Group > 0 && 2*r.Group+1 < len(m) && m[2*r.Group] >= 0 {
+			start, end = m[2*r.Group], m[2*r.Group+1]
+		}
+		secret := line[start:end]
+		if s.allowed(secret) || strings.HasPrefix(secret, maskPrefix) {
+			continue
+		}
+		b.WriteString(line[last:start])
+		b.WriteString(mask(r.Name))
+		last = end
+		report(r.Name, preview(secret))
+	}
+	b.WriteString(line[last:])
+	return b.String()
+}
+
+func (s *Scanner) allowed(secret string) bool {
+	for _, re := range s.allow {
+		if re.MatchString(secret) {
+			return true
+		}
+	}
+	return false
+}
+
+// Report 将检测结果格式化为便于阅读的报告
+func Report(findings []Finding) string {
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("检测到 %d 处疑似敏感信息:\n", len(findings)))
+	for _, f := range findings {
+		location := fmt.Sprintf("第 %d 行", f.Line)
+		if f.File != "" {
+			location = fmt.Sprintf("%s:%d", f.File, f.Line)
+		}
+		b.WriteString(fmt.Sprintf("  %-24s %-16s %s\n", location, f.Rule, f.Masked))
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// maskPrefix 是屏蔽占位符的前缀
+const maskPrefix = "<已屏蔽:"
+
+func mask(rule string) string {
+	return maskPrefix + rule + ">"
+}
+
+// ContainsMask 判断文本中是否含有屏蔽占位符，
+// 用于发现 AI 回复中照搬了被屏蔽的内容
+func ContainsMask(text string) bool {
+	return strings.Contains(text, maskPrefix)
+}
+
+// preview 只保留前 4 个字符，避免在报告中再次泄露
+func preview(secret string) string {
+	r := []rune(secret)
+	if len(r) <= 4 {
+		return "****"
+	}
+	return string(r[:4]) + "****"
+}
+
+func diffPrefix(line string, isDiff bool) string {
+	if isDiff && len(line) > 0 && strings.ContainsRune("+- ", rune(line[0])) {
+		return line[:1]
+	}
+	return ""
+}
+
+// isEnvFile 判断文件是否为 .env 配置文件，示例文件除外
+func isEnvFile(file string) bool {
+	base := path.Base(file)
+	if base != ".env" && !strings.HasPrefix(base, ".env.") {
+		return false
+	}
+	for _, suffix := range []string{".example", ".sample", ".template", ".dist"} {
+		if strings.HasSuffix(base, suffix) {
+			return false
+		}
+	}
+	return true
+}
+
+// looksRandom 要求字符串同时包含字母和数字，以排除普通单词和路径
+func looksRandom(s string) bool {
+	var letter, digit bool
+	for _, r := range s {
+		switch {
+		case r >= '0' && r <= '9':
+			digit = true
+		case (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z'):
+			letter = true
+		}
+	}
+	return letter && digit
+}
+
+// entropy 计算字符串的香农熵（每字符比特数）
+func entropy(s string) float64 {
+	counts := map[rune]int{}
+	for _, r := range s {
+		counts[r]++
+	}
+	var h float64
+	n := float64(len(s))
+	for _, c := range counts {
+		p := float64(c) / n
+		h -= p * math.Log2(p)
+	}
+	return h
+}
@@ -0,0 +1,245 @@
+package secrets
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// 测试用的密钥在运行时拼接，避免源码本身被密钥扫描工具拦截
+var (
+	awsKey     = "AKIA" + strings.Repeat("Q", 16)
+	githubTok  = "ghp_" + strings.Repeat("a", 36)
+	openaiKey  = "sk-" + strings.Repeat("x", 24)
+	slackTok   = "xoxb-" + strings.Repeat("1", 12)
+	googleKey  = "AIza" + strings.Repeat("B", 35)
+	jwtToken   = "eyJ" + strings.Repeat("h", 12) + "." + strings.Repeat("p", 12) + "." + strings.Repeat("s", 12)
+	randomTok  = "q8Zr3LmX0pWv7KsT2bNy9HdF"
+	privateKey = "-----BEGIN RSA " + "PRIVATE KEY-----"
+)
+
+func newScanner(t *testing.T, cfg Config) *Scanner {
+	t.Helper()
+	s, err := NewScanner(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestBuiltinRules(t *testing.T) {
+	tests := []struct {
+		rule string
+		text string
+		keep string
+	}{
+		{"aws-access-key", "key = " + awsKey, "key = "},
+		{"github-token", "token: " + githubTok, "token: "},
+		{"openai-key", "Authorization: Bearer " + openaiKey, "Authorization: Bearer "},
+		{"slack-token", "SLACK " + slackTok, "SLACK "},
+		{"google-api-key", "maps(" + googleKey + ")", "maps("},
+		{"jwt", "cookie " + jwtToken, "cookie "},
+		{"url-credentials", "postgres://admin:hunter2x@db:5432", "postgres://admin:"},
+		{"password", `password = "correct-horse"`, `password = "`},
+	}
+	s := newScanner(t, Config{EntropyThreshold: -1})
+	for _, tt := range tests {
+		masked, findings := s.Scan(tt.text)
+		if len(findings) != 1 || findings[0].Rule != tt.rule || findings[0].Line != 1 {
+			t.Errorf("%s: findings = %+v", tt.rule, findings)
+			continue
+		}
+		if !strings.Contains(masked, mask(tt.rule)) || !strings.HasPrefix(masked, tt.keep) {
+			t.Errorf("%s: masked = %q", tt.rule, masked)
+		}
+		if strings.Contains(findings[0].Masked, tt.text[len(tt.keep):]) {
+			t.Errorf("%s: report leaks the secret: %q", tt.rule, findings[0].Masked)
+		}
+	}
+}
+
+func TestConfig(t *testing.T) {
+	text := "key = " + awsKey + "\nid = CUSTOM-123456\n"
+	s := newScanner(t, Config{
+		Rules:            []Rule{{Name: "custom", Pattern: `CUSTOM-\d+`}},
+		Disable:          []string{"aws-access-key"},
+		EntropyThreshold: -1,
+	})
+	masked, findings := s.Scan(text)
+	if len(findings) != 1 || findings[0].Rule != "custom" || findings[0].Line != 2 {
+		t.Errorf("findings = %+v", findings)
+	}
+	if !strings.Contains(masked, awsKey) {
+		t.Errorf("disabled rule should not mask: %q", masked)
+	}
+
+	s = newScanner(t, Config{Allow: []string{`^sk-x+$`}})
+	if _, findings := s.Scan("OPENAI=" + openaiKey); len(findings) != 0 {
+		t.Errorf("allowed secret reported: %+v", findings)
+	}
+
+	if _, err := NewScanner(Config{Rules: []Rule{{Name: "bad", Pattern: "("}}}); err == nil {
+		t.Error("invalid rule pattern should fail")
+	}
+}
+
+func TestEntropy(t *testing.T) {
+	tests := []struct {
+		name      string
+		threshold float64
+		text      string
+		found     bool
+	}{
+		{"random token", 0, "id: " + randomTok, true},
+		{"repetitive token", 0, "id: abab1212abab1212abab1212", false},
+		{"words without digits", 0, "id: SomeVeryLongIdentifierName", false},
+		{"disabled", -1, "id: " + randomTok, false},
+		{"higher threshold", 5, "id: " + randomTok, false},
+	}
+	for _, tt := range tests {
+		s := newScanner(t, Config{EntropyThreshold: tt.threshold})
+		masked, findings := s.Scan(tt.text)
+		if (len(findings) > 0) != tt.found {
+			t.Errorf("%s: findings = %+v, want found=%v", tt.name, findings, tt.found)
+		}
+		if tt.found && masked != "id: "+mask("high-entropy") {
+			t.Errorf("%s: masked = %q", tt.name, masked)
+		}
+	}
+}
+
+func TestPrivateKey(t *testing.T) {
+	text := "before\n" + privateKey + "\nMIIEpAIBAAKCAQEA\nabcdefgh\n-----END RSA PRIVATE KEY-----\nafter"
+	s := newScanner(t, Config{})
+	masked, findings := s.Scan(text)
+	if len(findings) != 1 || findings[0].Rule != "private-key" || findings[0].Line != 2 {
+		t.Errorf("findings = %+v", findings)
+	}
+	want := "before\n" + privateKey + "\n" + mask("private-key") + "\n" + mask("private-key") + "\n-----END RSA PRIVATE KEY-----\nafter"
+	if masked != want {
+		t.Errorf("masked = %q, want %q", masked, want)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	diff := strings.Join([]string{
+		"diff --git a/.env b/.env",
+		"index 111..222 100644",
+		"--- a/.env",
+		"+++ b/.env",
+		"@@ -1,2 +1,2 @@",
+		" DEBUG=true",
+		"-API_TOKEN=old-value",
+		"+API_TOKEN=new-value",
+		"diff --git a/notes.md b/notes.md",
+		"--- a/notes.md",
+		"+++ b/notes.md",
+		"@@ -10,2 +10,3 @@",
+		" text",
+		"--- password: hunter2hunter",
+		"+++ " + githubTok,
+		"diff --git a/.env.example b/.env.example",
+		"@@ -1 +1 @@",
+		"+API_TOKEN=changeme",
+	}, "\n")
+
+	s := newScanner(t, Config{EntropyThreshold: -1})
+	masked, findings := s.Scan(diff)
+
+	type loc struct {
+		rule string
+		file string
+		line int
+	}
+	var got []loc
+	for _, f := range findings {
+		got = append(got, loc{f.Rule, f.File, f.Line})
+	}
+	want := []loc{
+		// .env 中的上下文行同样会被屏蔽，删除行标注为新文件中的上一行
+		{"env-file", ".env", 1},
+		{"env-file", ".env", 1},
+		{"env-file", ".env", 2},
+		{"password", "notes.md", 10},
+		{"github-token", "notes.md", 11},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("findings = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("finding %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	for _, line := range []string{
+		"--- a/.env",
+		"+++ b/.env",
+		"-API_TOKEN=" + mask("env-file"),
+		"+API_TOKEN=" + mask("env-file"),
+		"--- password: " + mask("password"),
+		"+++ " + mask("github-token"),
+		"+API_TOKEN=changeme",
+	} {
+		if !strings.Contains(masked, line+"\n") && !strings.HasSuffix(masked, line) {
+			t.Errorf("masked diff missing %q:\n%s", line, masked)
+		}
+	}
+}
+
+func TestPreview(t *testing.T) {
+	tests := []struct {
+		secret string
+		want   string
+	}{
+		{"abc", "****"},
+		{"abcd", "****"},
+		{"abcdef", "abcd****"},
+		{"密码是很长的秘密", "密码是很****"},
+	}
+	for _, tt := range tests {
+		if got := preview(tt.secret); got != tt.want {
+			t.Errorf("preview(%q) = %q, want %q", tt.secret, got, tt.want)
+		}
+	}
+
+	s := newScanner(t, Config{EntropyThreshold: -1})
+	_, findings := s.Scan("password=密码是很长的秘密")
+	if len(findings) != 1 || findings[0].Masked != "密码是很****" {
+		t.Errorf("findings = %+v", findings)
+	}
+}
+
+func TestContainsMask(t *testing.T) {
+	if !ContainsMask("x "+mask("jwt")) || ContainsMask("x < y") {
+		t.Error("ContainsMask() returned unexpected results")
+	}
+}
+
+func TestLoadScannerExpandsHome(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	if err := os.MkdirAll(filepath.Join(home, ".config"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	cfg := `{"rules": [{"name": "custom", "pattern": "CUSTOM-\\d+"}]}`
+	if err := ioutil.WriteFile(filepath.Join(home, ".config", "secrets.json"), []byte(cfg), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("AICLI_SECRETS_CONFIG", "~/.config/secrets.json")
+	s, err := LoadScanner()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, findings := s.Scan("CUSTOM-42"); len(findings) != 1 || findings[0].Rule != "custom" {
+		t.Errorf("findings = %+v", findings)
+	}
+
+	t.Setenv("AICLI_SECRETS_CONFIG", filepath.Join(home, "missing.json"))
+	if _, err := LoadScanner(); err == nil {
+		t.Error("missing config file should fail")
+	}
+}
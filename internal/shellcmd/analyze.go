@@ -0,0 +1,517 @@
+package shellcmd
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Level 是命令的风险等级
+type Level int
+
+const (
+	// Safe 表示未发现风险
+	Safe Level = iota
+	// Warn 表示存在风险，执行前需要用户输入确认
+	Warn
+	// Block 表示极其危险，拒绝执行
+	Block
+)
+
+func (l Level) String() string {
+	switch l {
+	case Warn:
+		return "warn"
+	case Block:
+		return "block"
+	}
+	return "safe"
+}
+
+// Risk 是分析出的一项风险
+type Risk struct {
+	Level   Level
+	Rule    string
+	Message string
+	// Command 是触发规则的命令
+	Command string
+}
+
+// Report 是一段命令的分析结果，Level 为所有风险中的最高等级
+type Report struct {
+	Level Level
+	Risks []Risk
+}
+
+func (r *Report) add(level Level, rule, command, format string, args ...interface{}) {
+	r.Risks = append(r.Risks, Risk{Level: level, Rule: rule, Message: fmt.Sprintf(format, args...), Command: command})
+	if level > r.Level {
+		r.Level = level
+	}
+}
+
+// Analyze 解析命令并按内置规则检查风险。无法解析的命令视为 Warn，由用户自行确认。
+func Analyze(src string) Report {
+	var report Report
+	analyzeSource(&report, src, 0)
+	return report
+}
+
+// maxDepth 限制 sh -c 和命令替换的递归深度
+const maxDepth = 5
+
+var functionDef = regexp.MustCompile(`([\w:]+)\s*\(\)\s*\{([^}]*)\}`)
+
+// isForkBomb 判断是否定义了在函数体内以管道调用自身并放入后台的函数，例如 :(){ :|:& };:
+func isForkBomb(src string) bool {
+	for _, m := range functionDef.FindAllStringSubmatch(src, -1) {
+		body := strings.Join(strings.Fields(m[2]), "")
+		if strings.Contains(body, m[1]+"|"+m[1]+"&") {
+			return true
+		}
+	}
+	return false
+}
+
+func analyzeSource(report *Report, src string, depth int) {
+	if depth > maxDepth {
+		report.add(Warn, "nesting", src, "命令嵌套过深，无法完整分析")
+		return
+	}
+	if isForkBomb(src) {
+		report.add(Block, "fork-bomb", src, "疑似 fork 炸弹，会耗尽系统进程资源")
+	}
+
+	script, err := Parse(src)
+	if err != nil {
+		report.add(Warn, "parse", src, "无法解析命令（%v），请仔细检查后再执行", err)
+		return
+	}
+
+	for _, p := range script.Pipelines {
+		for i, stage := range p.Stages {
+			for _, sub := range stage.Substitutions {
+				analyzeSource(report, sub, depth+1)
+			}
+			checkRedirects(report, stage)
+
+			inner := unwrap(report, stage, depth)
+			if inner == nil {
+				continue
+			}
+			checkStage(report, *inner)
+
+			// 下载的内容直接交给解释器执行
+			if i > 0 && isInterpreter(inner.Name()) {
+				for _, prev := range p.Stages[:i] {
+					if name := commandName(prev); isDownloader(name) {
+						report.add(Warn, "pipe-to-shell", stage.String(), "将 %s 下载的内容直接交给 %s 执行，请确认来源可信", name, inner.Name())
+					}
+				}
+			}
+		}
+	}
+}
+
+// wrappers 是只负责启动其他命令的前缀命令，值为需要跳过的带参数选项
+var wrappers = map[string][]string{
+	"sudo":    {"-u", "-g", "-C", "-D", "-h", "-p", "-r", "-t", "-U"},
+	"doas":    {"-u", "-C"},
+	"env":     {"-u", "-C", "-S"},
+	"nohup":   nil,
+	"time":    {"-f", "-o"},
+	"nice":    {"-n"},
+	"ionice":  {"-c", "-n", "-p"},
+	"exec":    {"-a"},
+	"command": nil,
+	"builtin": nil,
+	"xargs":   {"-a", "-d", "-E", "-I", "-L", "-n", "-P", "-s", "--delimiter", "--max-args", "--max-procs", "--replace"},
+	"timeout": {"-k", "-s", "--kill-after", "--signal"},
+	"watch":   {"-n", "-d", "--interval"},
+	"stdbuf":  {"-i", "-o", "-e"},
+	"busybox": nil,
+}
+
+// reservedWords 是出现在命令位置的 shell 保留字，其后的单词才是真正执行的命令。
+// time 作为前缀命令在 wrappers 中处理。
+var reservedWords = map[string]bool{
+	"if": true, "then": true, "elif": true, "else": true, "fi": true,
+	"for": true, "while": true, "until": true, "do": true, "done": true,
+	"case": true, "esac": true, "select": true, "function": true,
+	"!": true, "{": true, "}": true, "[[": true,
+}
+
+// skipReservedWords 去掉命令开头的保留字，以及保留字之后的环境变量赋值
+func skipReservedWords(args []string) []string {
+	skipped := false
+	for len(args) > 0 && (reservedWords[args[0]] || skipped && isAssignment(args[0])) {
+		args = args[1:]
+		skipped = true
+	}
+	return args
+}
+
+// commandName 返回去掉保留字后的命令名
+func commandName(stage Stage) string {
+	return Stage{Args: skipReservedWords(stage.Args)}.Name()
+}
+
+func isDownloader(name string) bool {
+	return name == "curl" || name == "wget" || name == "fetch"
+}
+
+// substitutedDownload 返回脚本中命令替换里调用的下载命令，例如 sh -c "$(curl -fsSL URL)" 中的 curl；
+// 没有时返回空字符串
+func substitutedDownload(src string) string {
+	script, err := Parse(src)
+	if err != nil {
+		return ""
+	}
+	for _, stage := range script.Stages() {
+		for _, sub := range stage.Substitutions {
+			inner, err := Parse(sub)
+			if err != nil {
+				continue
+			}
+			for _, s := range inner.Stages() {
+				if name := commandName(s); isDownloader(name) {
+					return name
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// unwrap 去掉保留字和 sudo、env、xargs 等前缀，返回真正执行的命令；sh -c 中的脚本会递归分析。
+// 没有需要检查的命令时返回 nil。
+func unwrap(report *Report, stage Stage, depth int) *Stage {
+	for {
+		if args := skipReservedWords(stage.Args); len(args) != len(stage.Args) {
+			stage = Stage{Args: args, Redirects: stage.Redirects}
+		}
+		if len(stage.Args) == 0 {
+			return nil
+		}
+
+		name := stage.Name()
+		if name == "sudo" || name == "doas" {
+			report.add(Warn, "sudo", stage.String(), "使用 %s 以管理员权限执行", name)
+		}
+
+		if isShell(name) {
+			for i, arg := range stage.Args[1:] {
+				if arg == "-c" && i+2 < len(stage.Args) {
+					script := stage.Args[i+2]
+					if dl := substitutedDownload(script); dl != "" {
+						report.add(Warn, "pipe-to-shell", stage.String(), "将 %s 下载的内容直接交给 %s 执行，请确认来源可信", dl, name)
+					}
+					analyzeSource(report, script, depth+1)
+					return nil
+				}
+			}
+			return &stage
+		}
+		if name == "eval" {
+			script := strings.Join(stage.Args[1:], " ")
+			if dl := substitutedDownload(script); dl != "" {
+				report.add(Warn, "pipe-to-shell", stage.String(), "将 %s 下载的内容直接交给 eval 执行，请确认来源可信", dl)
+			}
+			analyzeSource(report, script, depth+1)
+			return nil
+		}
+
+		valueOpts, ok := wrappers[name]
+		if !ok {
+			return &stage
+		}
+
+		args := stage.Args[1:]
+		if name == "timeout" && len(args) > 0 {
+			args = skipOptions(args, valueOpts)
+			if len(args) > 0 {
+				args = args[1:] // 时长
+			}
+		} else {
+			args = skipOptions(args, valueOpts)
+		}
+		for len(args) > 0 && (name == "env" || name == "sudo") && isAssignment(args[0]) {
+			args = args[1:]
+		}
+		if len(args) == 0 {
+			return nil
+		}
+		stage = Stage{Args: args, Redirects: stage.Redirects}
+	}
+}
+
+func skipOptions(args []string, valueOpts []string) []string {
+	for len(args) > 0 && strings.HasPrefix(args[0], "-") {
+		if args[0] == "--" {
+			return args[1:]
+		}
+		takesValue := false
+		for _, opt := range valueOpts {
+			if args[0] == opt {
+				takesValue = true
+			}
+		}
+		args = args[1:]
+		if takesValue && len(args) > 0 {
+			args = args[1:]
+		}
+	}
+	return args
+}
+
+func isShell(name string) bool {
+	switch name {
+	case "sh", "bash", "zsh", "dash", "ksh", "fish":
+		return true
+	}
+	return false
+}
+
+func isInterpreter(name string) bool {
+	switch name {
+	case "sh", "bash", "zsh", "dash", "ksh", "fish", "python", "python3", "perl", "ruby", "node":
+		return true
+	}
+	return false
+}
+
+// systemDirs 是写入或递归修改后可能导致系统损坏的目录
+var systemDirs = []string{"/etc", "/boot", "/usr", "/bin", "/sbin", "/lib", "/lib64", "/var", "/sys", "/proc", "/opt", "/root", "/System", "/Library"}
+
+// criticalTargets 是删除后会导致系统或用户数据全部丢失的目标
+var criticalTargets = map[string]bool{
+	"/": true, "/*": true, "~": true, "~/": true, "~/*": true, "$HOME": true, "$HOME/": true, "$HOME/*": true,
+	"*": true, ".": true, "./": true, "..": true, "../": true, ".*": true,
+}
+
+var diskDevice = regexp.MustCompile(`^/dev/(sd[a-z]|hd[a-z]|vd[a-z]|xvd[a-z]|nvme\d|mmcblk\d|disk\d|md\d|dm-\d)`)
+
+func isSystemPath(p string) bool {
+	clean := path.Clean(p)
+	for _, dir := range systemDirs {
+		if clean == dir || strings.HasPrefix(clean, dir+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+var bracedVar = regexp.MustCompile(`\$\{(\w+)\}`)
+
+// isCritical 判断删除目标是否为根目录、主目录等关键位置。
+// ${VAR} 统一写作 $VAR，末尾多余的 / 会被去掉，如 ${HOME}/ 与 $HOME 等价。
+func isCritical(p string) bool {
+	p = bracedVar.ReplaceAllString(p, "$$$1")
+	if trimmed := strings.TrimRight(p, "/"); trimmed != "" {
+		p = trimmed
+	} else if p != "" {
+		p = "/"
+	}
+	if criticalTargets[p] {
+		return true
+	}
+	clean := path.Clean(strings.TrimSuffix(p, "/*"))
+	if clean == "/" || criticalTargets[clean] {
+		return true
+	}
+	for _, dir := range systemDirs {
+		if clean == dir {
+			return true
+		}
+	}
+	return clean == "/home" || clean == "/Users"
+}
+
+func checkRedirects(report *Report, stage Stage) {
+	for _, r := range stage.Redirects {
+		if !strings.HasPrefix(r.Op, ">") && !strings.HasPrefix(r.Op, "&>") && r.Op != "<>" {
+			continue
+		}
+		switch {
+		case diskDevice.MatchString(r.Target):
+			report.add(Block, "write-device", stage.String(), "直接写入磁盘设备 %s 会破坏其中的数据", r.Target)
+		case isSystemPath(r.Target):
+			report.add(Warn, "write-system", stage.String(), "写入系统路径 %s", r.Target)
+		}
+	}
+}
+
+// flags 收集单字母短选项和长选项
+func flags(args []string) map[string]bool {
+	set := map[string]bool{}
+	for _, arg := range args {
+		switch {
+		case arg == "--":
+			return set
+		case strings.HasPrefix(arg, "--"):
+			set[strings.SplitN(arg, "=", 2)[0]] = true
+		case strings.HasPrefix(arg, "-") && len(arg) > 1:
+			for _, r := range arg[1:] {
+				set["-"+string(r)] = true
+			}
+		}
+	}
+	return set
+}
+
+func operands(args []string) []string {
+	var result []string
+	for _, arg := range args {
+		if !strings.HasPrefix(arg, "-") || arg == "-" {
+			result = append(result, arg)
+		}
+	}
+	return result
+}
+
+// checkStage 按规则检查一条命令，只有重定向没有命令的部分已由 checkRedirects 检查
+func checkStage(report *Report, stage Stage) {
+	if len(stage.Args) == 0 {
+		return
+	}
+	name := stage.Name()
+	args := stage.Args[1:]
+	f := flags(args)
+	cmd := stage.String()
+
+	switch {
+	case name == "rm":
+		recursive := f["-r"] || f["-R"] || f["--recursive"]
+		if f["--no-preserve-root"] {
+			report.add(Block, "rm-root", cmd, "rm 使用了 --no-preserve-root")
+			return
+		}
+		for _, target := range operands(args) {
+			if recursive && isCritical(target) {
+				report.add(Block, "rm-critical", cmd, "递归删除 %s 会导致系统或用户数据全部丢失", target)
+				return
+			}
+		}
+		if recursive {
+			report.add(Warn, "rm-recursive", cmd, "递归删除文件，删除后无法恢复")
+		} else if f["-f"] || f["--force"] {
+			report.add(Warn, "rm-force", cmd, "强制删除文件，删除后无法恢复")
+		}
+		for _, target := range operands(args) {
+			if isSystemPath(target) {
+				report.add(Warn, "rm-system", cmd, "删除系统路径 %s", target)
+			}
+		}
+	case name == "dd":
+		for _, arg := range args {
+			if strings.HasPrefix(arg, "of=") {
+				target := strings.TrimPrefix(arg, "of=")
+				if diskDevice.MatchString(target) {
+					report.add(Block, "dd-device", cmd, "dd 直接写入磁盘设备 %s 会覆盖其中的数据", target)
+				} else {
+					report.add(Warn, "dd", cmd, "dd 会直接覆盖 %s", target)
+				}
+			}
+		}
+	case strings.HasPrefix(name, "mkfs") || name == "mke2fs" || name == "wipefs" || name == "mkswap":
+		report.add(Block, "mkfs", cmd, "%s 会格式化设备，擦除其中的全部数据", name)
+	case name == "fdisk" || name == "sfdisk" || name == "parted" || name == "gdisk" || name == "diskutil":
+		report.add(Warn, "partition", cmd, "%s 会修改磁盘分区", name)
+	case name == "shred":
+		report.add(Warn, "shred", cmd, "shred 会不可恢复地覆盖文件内容")
+	case name == "chmod" || name == "chown" || name == "chgrp":
+		recursive := f["-R"] || f["--recursive"]
+		// 第一个操作数是权限或属主，其余为目标路径
+		var targets []string
+		if ops := operands(args); len(ops) > 1 {
+			targets = ops[1:]
+		}
+		for _, target := range targets {
+			if recursive && isCritical(target) {
+				report.add(Block, name+"-critical", cmd, "递归修改 %s 的权限会破坏系统", target)
+				return
+			}
+		}
+		if recursive {
+			report.add(Warn, name+"-recursive", cmd, "%s -R 会递归修改所有文件的权限或属主", name)
+		}
+		if ops := operands(args); name == "chmod" && len(ops) > 0 && strings.HasSuffix(ops[0], "777") {
+			report.add(Warn, "chmod-777", cmd, "777 权限允许任何用户读写和执行")
+		}
+		checkSystemOperands(report, cmd, targets)
+	case name == "tee":
+		checkSystemOperands(report, cmd, operands(args))
+	case name == "cp" || name == "mv" || name == "ln" || name == "install" || name == "rsync":
+		if ops := operands(args); len(ops) > 0 {
+			checkSystemOperands(report, cmd, ops[len(ops)-1:])
+		}
+		if name == "rsync" && f["--delete"] {
+			report.add(Warn, "rsync-delete", cmd, "rsync --delete 会删除目标中多余的文件")
+		}
+	case name == "sed" && (f["-i"] || f["--in-place"]):
+		checkSystemOperands(report, cmd, operands(args))
+	case name == "find" && (containsArg(args, "-delete") || containsArg(args, "rm")):
+		report.add(Warn, "find-delete", cmd, "find 会批量删除匹配的文件")
+	case name == "truncate":
+		report.add(Warn, "truncate", cmd, "truncate 会截断文件内容")
+	case name == "shutdown" || name == "reboot" || name == "halt" || name == "poweroff":
+		report.add(Warn, "power", cmd, "%s 会关闭或重启机器", name)
+	case name == "kill" && containsArg(args, "-1"), name == "killall", name == "pkill":
+		report.add(Warn, "kill", cmd, "会结束多个进程")
+	case name == "crontab" && f["-r"]:
+		report.add(Warn, "crontab", cmd, "crontab -r 会删除当前用户的全部定时任务")
+	case name == "iptables" && (f["-F"] || f["--flush"]):
+		report.add(Warn, "iptables", cmd, "清空防火墙规则")
+	case name == "git":
+		checkGit(report, cmd, args)
+	case name == "docker" && containsArg(args, "prune"):
+		report.add(Warn, "docker-prune", cmd, "docker prune 会删除容器、镜像或数据卷")
+	case name == "kubectl" && len(args) > 0 && args[0] == "delete":
+		report.add(Warn, "kubectl-delete", cmd, "kubectl delete 会删除集群中的资源")
+	}
+}
+
+func checkGit(report *Report, cmd string, args []string) {
+	if len(args) == 0 {
+		return
+	}
+	f := flags(args[1:])
+	switch args[0] {
+	case "push":
+		if f["-f"] || f["--force"] || f["--force-with-lease"] || f["--mirror"] || f["--delete"] {
+			report.add(Warn, "git-force-push", cmd, "强制推送或删除远程分支会覆盖远程提交")
+		}
+	case "reset":
+		if f["--hard"] {
+			report.add(Warn, "git-reset-hard", cmd, "git reset --hard 会丢弃未提交的修改")
+		}
+	case "clean":
+		if f["-f"] || f["--force"] {
+			report.add(Warn, "git-clean", cmd, "git clean 会删除未跟踪的文件")
+		}
+	case "checkout", "restore":
+		if containsArg(args, ".") || f["-f"] || f["--force"] {
+			report.add(Warn, "git-discard", cmd, "会丢弃工作区中未提交的修改")
+		}
+	}
+}
+
+func checkSystemOperands(report *Report, cmd string, ops []string) {
+	for _, target := range ops {
+		if diskDevice.MatchString(target) {
+			report.add(Block, "write-device", cmd, "直接写入磁盘设备 %s 会破坏其中的数据", target)
+		} else if isSystemPath(target) {
+			report.add(Warn, "write-system", cmd, "修改系统路径 %s", target)
+		}
+	}
+}
+
+func containsArg(args []string, want string) bool {
+	for _, arg := range args {
+		if arg == want {
+			return true
+		}
+	}
+	return false
+}
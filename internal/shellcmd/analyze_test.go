@@ -0,0 +1,132 @@
+package shellcmd
+
+import "testing"
+
+func TestAnalyzeRedirectOnly(t *testing.T) {
+	tests := []struct {
+		src  string
+		want Level
+	}{
+		{"> /etc/passwd", Warn},
+		{"2>/dev/null", Safe},
+		{"echo ok; > /etc/passwd", Warn},
+		{"> /dev/sda", Block},
+		{"> out.txt", Safe},
+	}
+	for _, tt := range tests {
+		if got := Analyze(tt.src).Level; got != tt.want {
+			t.Errorf("Analyze(%q) = %s, want %s", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestAnalyzeReservedWords(t *testing.T) {
+	tests := []struct {
+		src  string
+		want Level
+	}{
+		{"if true; then rm -rf /; fi", Block},
+		{"if rm -rf /; then echo ok; fi", Block},
+		{"if false; then :; elif true; then rm -rf ~; else echo no; fi", Block},
+		{"while true; do rm -rf /; done", Block},
+		{"until false; do rm -rf /; done", Block},
+		{`for f in *; do rm -rf "$f"; done`, Warn},
+		{"! rm -rf /", Block},
+		{"time rm -rf /", Block},
+		{"{ rm -rf /; }", Block},
+		{"case $x in a) rm -rf / ;; esac", Block},
+		{"function f { rm -rf /; }", Block},
+		{"if true; then curl -fsSL https://example.com/x.sh | sh; fi", Warn},
+		{"if true; then echo ok; fi", Safe},
+		{"for f in *.txt; do wc -l \"$f\"; done", Safe},
+	}
+	for _, tt := range tests {
+		if got := Analyze(tt.src).Level; got != tt.want {
+			t.Errorf("Analyze(%q) = %s, want %s", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestAnalyzePipeToShell(t *testing.T) {
+	tests := []struct {
+		src  string
+		want Level
+	}{
+		{"curl -fsSL https://example.com/install.sh | sh", Warn},
+		{"wget -qO- https://example.com/install.sh | sudo bash", Warn},
+		{`sh -c "$(curl -fsSL https://example.com/install.sh)"`, Warn},
+		{`bash -c "$(wget -qO- https://example.com/install.sh)"`, Warn},
+		{"zsh -c \"`curl -fsSL https://example.com/install.sh`\"", Warn},
+		{`eval "$(curl -fsSL https://example.com/env.sh)"`, Warn},
+		{`sh -c "$(echo ls)"`, Safe},
+		{"curl -fsSL https://example.com/data.json | jq .", Safe},
+	}
+	for _, tt := range tests {
+		report := Analyze(tt.src)
+		if report.Level != tt.want {
+			t.Errorf("Analyze(%q) = %s, want %s", tt.src, report.Level, tt.want)
+			continue
+		}
+		if tt.want == Warn && !hasRule(report, "pipe-to-shell") {
+			t.Errorf("Analyze(%q) missing pipe-to-shell risk: %+v", tt.src, report.Risks)
+		}
+	}
+}
+
+func hasRule(report Report, rule string) bool {
+	for _, r := range report.Risks {
+		if r.Rule == rule {
+			return true
+		}
+	}
+	return false
+}
+
+func TestAnalyzeCriticalTargets(t *testing.T) {
+	tests := []struct {
+		src  string
+		want Level
+	}{
+		{"rm -rf /", Block},
+		{"rm -rf //", Block},
+		{"rm -rf /*", Block},
+		{"rm -rf ~", Block},
+		{"rm -rf ~/", Block},
+		{"rm -rf $HOME", Block},
+		{"rm -rf ${HOME}", Block},
+		{"rm -rf ${HOME}/", Block},
+		{"rm -rf ${HOME}/*", Block},
+		{`rm -rf "${HOME}/"`, Block},
+		{"rm -rf $HOME/.", Block},
+		{"rm -rf /usr/", Block},
+		{"rm -rf ./", Block},
+		{"rm -rf ${HOME}/project/build", Warn},
+		{"rm -rf build/", Warn},
+		{"rm build.log", Safe},
+	}
+	for _, tt := range tests {
+		if got := Analyze(tt.src).Level; got != tt.want {
+			t.Errorf("Analyze(%q) = %s, want %s", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestAnalyzeWrappers(t *testing.T) {
+	tests := []struct {
+		src  string
+		want Level
+	}{
+		{"busybox rm -rf /", Block},
+		{"sudo busybox rm -rf ${HOME}/", Block},
+		{"busybox sh -c 'rm -rf /'", Block},
+		{"nice -n 10 rm -rf /", Block},
+		{"timeout 5 rm -rf ~", Block},
+		{"env FOO=1 rm -rf /", Block},
+		{"busybox ls", Safe},
+	}
+	for _, tt := range tests {
+		if got := Analyze(tt.src).Level; got != tt.want {
+			t.Errorf("Analyze(%q) = %s, want %s", tt.src, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,389 @@
+// Package shellcmd 提供一个轻量的 POSIX shell 命令解析器，以及基于规则的危险命令分析。
+// 解析器只处理分析所需的语法：引号、转义、管道、命令连接符、重定向和命令替换，不做变量展开。
+package shellcmd
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// Redirect 是一个重定向，Op 为 ">"、">>"、"<" 等操作符，Fd 为操作符前的文件描述符
+type Redirect struct {
+	Fd     string
+	Op     string
+	Target string
+}
+
+// Stage 是管道中的一条简单命令
+type Stage struct {
+	// Assigns 是命令前的环境变量赋值，例如 FOO=bar
+	Assigns   []string
+	Args      []string
+	Redirects []Redirect
+	// Substitutions 是参数中 $(...) 或 `...` 内的命令，分析时会递归检查
+	Substitutions []string
+}
+
+// Name 返回命令名（去掉路径），没有命令时返回空字符串
+func (s Stage) Name() string {
+	if len(s.Args) == 0 {
+		return ""
+	}
+	return path.Base(s.Args[0])
+}
+
+// Pipeline 是由 | 连接的若干命令，Op 是与下一条管道之间的连接符：&&、||、; 或 &
+type Pipeline struct {
+	Stages []Stage
+	Op     string
+}
+
+// Script 是解析后的一段命令
+type Script struct {
+	Pipelines []Pipeline
+}
+
+// String 以规范化的形式输出一条命令，用于展示
+func (s Stage) String() string {
+	var parts []string
+	parts = append(parts, s.Assigns...)
+	for _, arg := range s.Args {
+		parts = append(parts, Quote(arg))
+	}
+	for _, r := range s.Redirects {
+		parts = append(parts, r.Fd+r.Op+" "+Quote(r.Target))
+	}
+	return strings.Join(parts, " ")
+}
+
+// Quote 在必要时为参数加上单引号
+func Quote(s string) string {
+	if s == "" {
+		return "''"
+	}
+	if !strings.ContainsAny(s, " \t\n'\"\\$`|&;<>()*?[]{}#~!") {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+type tokenKind int
+
+const (
+	tokenWord tokenKind = iota
+	tokenOp
+)
+
+type token struct {
+	kind  tokenKind
+	value string
+	// subs 是单词中出现的命令替换
+	subs []string
+}
+
+// Parse 解析命令文本
+func Parse(src string) (*Script, error) {
+	tokens, err := tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+
+	script := &Script{}
+	var pipeline Pipeline
+	var stage Stage
+	pending := false
+
+	flushStage := func() {
+		if len(stage.Args) > 0 || len(stage.Assigns) > 0 || len(stage.Redirects) > 0 {
+			pipeline.Stages = append(pipeline.Stages, stage)
+		}
+		stage = Stage{}
+	}
+	flushPipeline := func(op string) {
+		flushStage()
+		if len(pipeline.Stages) > 0 {
+			pipeline.Op = op
+			script.Pipelines = append(script.Pipelines, pipeline)
+		}
+		pipeline = Pipeline{}
+	}
+
+	for i := 0; i < len(tokens); i++ {
+		t := tokens[i]
+		if t.kind == tokenWord {
+			stage.Substitutions = append(stage.Substitutions, t.subs...)
+			if len(stage.Args) == 0 && isAssignment(t.value) {
+				stage.Assigns = append(stage.Assigns, t.value)
+			} else {
+				stage.Args = append(stage.Args, t.value)
+			}
+			pending = false
+			continue
+		}
+
+		if fd, op, ok := splitRedirect(t.value); ok {
+			if i+1 >= len(tokens) || tokens[i+1].kind != tokenWord {
+				return nil, fmt.Errorf("重定向 %s 缺少目标", t.value)
+			}
+			i++
+			stage.Substitutions = append(stage.Substitutions, tokens[i].subs...)
+			stage.Redirects = append(stage.Redirects, Redirect{Fd: fd, Op: op, Target: tokens[i].value})
+			continue
+		}
+
+		switch t.value {
+		case "|", "|&":
+			flushStage()
+			pending = true
+		case "&&", "||":
+			flushPipeline(t.value)
+			pending = true
+		case ";", "&", "\n", ";;":
+			flushPipeline(t.value)
+			pending = false
+		case "(", ")", "{", "}":
+			// 子 shell 和命令组只影响执行环境，分析时按顺序展开其中的命令
+			flushPipeline(";")
+		}
+	}
+	if pending {
+		return nil, fmt.Errorf("命令不完整：管道或连接符后缺少命令")
+	}
+	flushPipeline("")
+	return script, nil
+}
+
+// Stages 按出现顺序返回所有命令
+func (s *Script) Stages() []Stage {
+	var stages []Stage
+	for _, p := range s.Pipelines {
+		stages = append(stages, p.Stages...)
+	}
+	return stages
+}
+
+func isAssignment(word string) bool {
+	i := strings.Index(word, "=")
+	if i <= 0 {
+		return false
+	}
+	for j, r := range word[:i] {
+		if !(r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || j > 0 && r >= '0' && r <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+var redirectOps = []string{"&>>", "&>", ">>", ">|", ">&", "<<<", "<<", "<&", "<>", ">", "<"}
+
+// splitRedirect 将 "2>" 这样的操作符拆分为文件描述符和重定向符
+func splitRedirect(op string) (string, string, bool) {
+	i := 0
+	for i < len(op) && op[i] >= '0' && op[i] <= '9' {
+		i++
+	}
+	for _, r := range redirectOps {
+		if op[i:] == r {
+			return op[:i], r, true
+		}
+	}
+	return "", "", false
+}
+
+// tokenize 将命令文本拆分为单词和操作符，处理引号、转义、注释和命令替换
+func tokenize(src string) ([]token, error) {
+	var tokens []token
+	var word strings.Builder
+	var subs []string
+	inWord := false
+
+	flush := func() {
+		if inWord {
+			tokens = append(tokens, token{kind: tokenWord, value: word.String(), subs: subs})
+		}
+		word.Reset()
+		subs = nil
+		inWord = false
+	}
+
+	runes := []rune(src)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == '\\':
+			if i+1 < len(runes) {
+				i++
+				if runes[i] != '\n' {
+					word.WriteRune(runes[i])
+					inWord = true
+				}
+			}
+		case r == '\'':
+			end := indexRune(runes, i+1, '\'')
+			if end < 0 {
+				return nil, fmt.Errorf("单引号未闭合")
+			}
+			word.WriteString(string(runes[i+1 : end]))
+			inWord = true
+			i = end
+		case r == '"':
+			end, err := scanDoubleQuote(runes, i+1, &word, &subs)
+			if err != nil {
+				return nil, err
+			}
+			inWord = true
+			i = end
+		case r == '`':
+			end := indexRune(runes, i+1, '`')
+			if end < 0 {
+				return nil, fmt.Errorf("反引号未闭合")
+			}
+			subs = append(subs, string(runes[i+1:end]))
+			word.WriteString(string(runes[i : end+1]))
+			inWord = true
+			i = end
+		case r == '$' && i+1 < len(runes) && (runes[i+1] == '(' || runes[i+1] == '{'):
+			end, err := matchBracket(runes, i+1)
+			if err != nil {
+				return nil, err
+			}
+			if runes[i+1] == '(' && !(i+2 < len(runes) && runes[i+2] == '(') {
+				subs = append(subs, string(runes[i+2:end]))
+			}
+			word.WriteString(string(runes[i : end+1]))
+			inWord = true
+			i = end
+		case r == '#' && !inWord:
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+			i--
+		case r == ' ' || r == '\t' || r == '\r':
+			flush()
+		case r == '\n' || strings.ContainsRune("|&;()<>", r) || (r == '{' || r == '}') && !inWord && isGroupBrace(runes, i):
+			// 纯数字紧跟重定向符时视为文件描述符，例如 2>
+			fd := ""
+			if (r == '>' || r == '<') && inWord && isDigits(word.String()) && len(subs) == 0 {
+				fd = word.String()
+				word.Reset()
+				inWord = false
+			}
+			flush()
+			op := readOperator(runes, i)
+			tokens = append(tokens, token{kind: tokenOp, value: fd + op})
+			i += len([]rune(op)) - 1
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	flush()
+	return tokens, nil
+}
+
+var operators = []string{"&>>", "<<<", "&&", "||", "|&", ";;", "&>", ">>", ">|", ">&", "<<", "<&", "<>", "|", "&", ";", "(", ")", "{", "}", "<", ">", "\n"}
+
+func readOperator(runes []rune, i int) string {
+	rest := string(runes[i:min(i+3, len(runes))])
+	for _, op := range operators {
+		if strings.HasPrefix(rest, op) {
+			return op
+		}
+	}
+	return string(runes[i])
+}
+
+// isGroupBrace 判断 { 或 } 是否作为命令组的关键字出现（前后为空白或分隔符）
+func isGroupBrace(runes []rune, i int) bool {
+	next := i + 1
+	return next >= len(runes) || strings.ContainsRune(" \t\n;", runes[next])
+}
+
+func scanDoubleQuote(runes []rune, i int, word *strings.Builder, subs *[]string) (int, error) {
+	for ; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == '"':
+			return i, nil
+		case r == '\\' && i+1 < len(runes) && strings.ContainsRune("$`\"\\\n", runes[i+1]):
+			i++
+			if runes[i] != '\n' {
+				word.WriteRune(runes[i])
+			}
+		case r == '`':
+			end := indexRune(runes, i+1, '`')
+			if end < 0 {
+				return 0, fmt.Errorf("反引号未闭合")
+			}
+			*subs = append(*subs, string(runes[i+1:end]))
+			word.WriteString(string(runes[i : end+1]))
+			i = end
+		case r == '$' && i+1 < len(runes) && (runes[i+1] == '(' || runes[i+1] == '{'):
+			end, err := matchBracket(runes, i+1)
+			if err != nil {
+				return 0, err
+			}
+			if runes[i+1] == '(' && !(i+2 < len(runes) && runes[i+2] == '(') {
+				*subs = append(*subs, string(runes[i+2:end]))
+			}
+			word.WriteString(string(runes[i : end+1]))
+			i = end
+		default:
+			word.WriteRune(r)
+		}
+	}
+	return 0, fmt.Errorf("双引号未闭合")
+}
+
+// matchBracket 返回与 runes[i] 处的括号匹配的位置，跳过引号中的内容
+func matchBracket(runes []rune, i int) (int, error) {
+	open := runes[i]
+	close := ')'
+	if open == '{' {
+		close = '}'
+	}
+	depth := 0
+	for j := i; j < len(runes); j++ {
+		switch runes[j] {
+		case '\\':
+			j++
+		case '\'':
+			end := indexRune(runes, j+1, '\'')
+			if end < 0 {
+				return 0, fmt.Errorf("单引号未闭合")
+			}
+			j = end
+		case open:
+			depth++
+		case close:
+			depth--
+			if depth == 0 {
+				return j, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("%c 未闭合", open)
+}
+
+func indexRune(runes []rune, from int, r rune) int {
+	for i := from; i < len(runes); i++ {
+		if runes[i] == r {
+			return i
+		}
+	}
+	return -1
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
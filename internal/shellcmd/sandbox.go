@@ -0,0 +1,43 @@
+package shellcmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+)
+
+// DryRunAvailable 检查能否在沙箱中试运行命令，不可用时返回原因。目前仅支持安装了 bubblewrap 的 Linux。
+func DryRunAvailable() error {
+	if runtime.GOOS != "linux" {
+		return fmt.Errorf("沙箱试运行仅支持 Linux")
+	}
+	if _, err := exec.LookPath("bwrap"); err != nil {
+		return fmt.Errorf("未安装 bubblewrap (bwrap)，无法进行沙箱试运行")
+	}
+	return nil
+}
+
+// DryRunCommand 构造在沙箱中执行命令的 exec.Cmd：根文件系统只读挂载，/tmp 为空的临时文件系统，
+// 网络和进程等命名空间相互隔离，工作目录保持为 dir（只读）。命令中的写入操作会失败而不会真正生效。
+func DryRunCommand(shell, command, dir string) (*exec.Cmd, error) {
+	if err := DryRunAvailable(); err != nil {
+		return nil, err
+	}
+	args := []string{
+		"--ro-bind", "/", "/",
+		"--dev", "/dev",
+		"--proc", "/proc",
+		"--tmpfs", "/tmp",
+		"--unshare-all",
+		"--die-with-parent",
+		"--chdir", dir,
+		"--setenv", "TMPDIR", "/tmp",
+		shell, "-c", command,
+	}
+	c := exec.Command("bwrap", args...)
+	c.Stdin = os.Stdin
+	c.Stdout = os.Stdout
+	c.Stderr = os.Stderr
+	return c, nil
+}
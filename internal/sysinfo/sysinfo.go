@@ -0,0 +1,183 @@
+// Package sysinfo 收集生成命令时用到的本机环境信息
+package sysinfo
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/fanook/aicli/internal/githelper"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// CommonTools 是需要检测是否安装的常用命令行工具
+var CommonTools = []string{"jq", "rg", "fd", "fzf", "docker", "kubectl", "git", "curl", "wget", "python3", "awk", "sed"}
+
+// maxListing 是目录摘要中最多列出的条目数
+const maxListing = 20
+
+// Info 是本机环境信息，字段可直接在提示模板中使用
+type Info struct {
+	OS     string
+	Arch   string
+	Shell  string
+	Distro string
+	Cwd    string
+	// Listing 是当前目录内容的摘要
+	Listing   string
+	InGitRepo bool
+	GitBranch string
+	// Tools 和 MissingTools 是 CommonTools 中已安装和未安装的工具，以逗号分隔
+	Tools        string
+	MissingTools string
+}
+
+// Collect 收集本机环境信息，获取失败的字段保持为空
+func Collect() Info {
+	info := Info{
+		OS:     runtime.GOOS,
+		Arch:   runtime.GOARCH,
+		Shell:  shell(),
+		Distro: distro(),
+	}
+
+	if cwd, err := os.Getwd(); err == nil {
+		info.Cwd = cwd
+		info.Listing = listing(cwd)
+	}
+
+	if repo, err := githelper.Open(""); err == nil {
+		info.InGitRepo = true
+		info.GitBranch, _ = repo.CurrentBranch()
+	}
+
+	var installed, missing []string
+	for _, tool := range CommonTools {
+		if _, err := exec.LookPath(tool); err == nil {
+			installed = append(installed, tool)
+		} else {
+			missing = append(missing, tool)
+		}
+	}
+	info.Tools = strings.Join(installed, ", ")
+	info.MissingTools = strings.Join(missing, ", ")
+	return info
+}
+
+func shell() string {
+	if sh := os.Getenv("SHELL"); sh != "" {
+		return filepath.Base(sh)
+	}
+	if runtime.GOOS == "windows" {
+		if os.Getenv("PSModulePath") != "" {
+			return "powershell"
+		}
+		return "cmd"
+	}
+	return "sh"
+}
+
+// distro 返回操作系统发行版名称，Linux 读取 /etc/os-release，macOS 使用 sw_vers
+func distro() string {
+	switch runtime.GOOS {
+	case "linux":
+		return parseOSRelease("/etc/os-release")
+	case "darwin":
+		out, err := exec.Command("sw_vers", "-productVersion").Output()
+		if err != nil {
+			return "macOS"
+		}
+		return "macOS " + strings.TrimSpace(string(out))
+	}
+	return ""
+}
+
+func parseOSRelease(path string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	values := map[string]string{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), "=", 2)
+		if len(parts) == 2 {
+			values[parts[0]] = strings.Trim(parts[1], `"'`)
+		}
+	}
+	if name := values["PRETTY_NAME"]; name != "" {
+		return name
+	}
+	return strings.TrimSpace(values["NAME"] + " " + values["VERSION_ID"])
+}
+
+// listing 汇总目录中的文件和子目录数量、主要文件类型以及部分条目名称
+func listing(dir string) string {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return ""
+	}
+
+	files, dirs := 0, 0
+	exts := map[string]int{}
+	var names []string
+	for _, e := range entries {
+		if e.IsDir() {
+			dirs++
+		} else {
+			files++
+			if ext := filepath.Ext(e.Name()); ext != "" {
+				exts[ext]++
+			}
+		}
+		if strings.HasPrefix(e.Name(), ".") && e.Name() != ".git" {
+			continue
+		}
+		if len(names) < maxListing {
+			name := e.Name()
+			if e.IsDir() {
+				name += "/"
+			}
+			names = append(names, name)
+		}
+	}
+
+	summary := fmt.Sprintf("%d 个文件，%d 个目录", files, dirs)
+	if top := topExtensions(exts, 5); top != "" {
+		summary += "；主要文件类型: " + top
+	}
+	if len(names) > 0 {
+		summary += "；包含: " + strings.Join(names, " ")
+		if len(entries) > len(names) {
+			summary += " ..."
+		}
+	}
+	return summary
+}
+
+func topExtensions(exts map[string]int, n int) string {
+	var keys []string
+	for ext := range exts {
+		keys = append(keys, ext)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if exts[keys[i]] != exts[keys[j]] {
+			return exts[keys[i]] > exts[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	if len(keys) > n {
+		keys = keys[:n]
+	}
+	var parts []string
+	for _, ext := range keys {
+		parts = append(parts, fmt.Sprintf("%s(%d)", ext, exts[ext]))
+	}
+	return strings.Join(parts, " ")
+}
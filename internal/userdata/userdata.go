@@ -0,0 +1,31 @@
+package userdata
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// Dir 返回 aicli 保存本地数据的目录，可通过 AICLI_HOME 环境变量指定
+func Dir() (string, error) {
+	dir := os.Getenv("AICLI_HOME")
+	if dir == "" {
+		configDir, err := os.UserConfigDir()
+		if err != nil {
+			return "", err
+		}
+		dir = filepath.Join(configDir, "aicli")
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// Path 返回数据目录下指定文件的路径
+func Path(name string) (string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, name), nil
+}